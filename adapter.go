@@ -0,0 +1,115 @@
+package tsing
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// mountParam Mount() 注册的路由中通配参数的名称
+const mountParam = "mountpath"
+
+// mountMethods Mount() 注册路由的请求方法
+var mountMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+type originalPathKey struct{}
+
+// WrapHandler 将 http.Handler 转为 Handler
+func WrapHandler(h http.Handler) Handler {
+	return func(ctx *Context) error {
+		h.ServeHTTP(ctx.ResponseWriter, ctx.Request)
+		return nil
+	}
+}
+
+// WrapHandlerFunc 将 http.HandlerFunc 转为 Handler
+func WrapHandlerFunc(f http.HandlerFunc) Handler {
+	return WrapHandler(f)
+}
+
+type stdStateKey struct{}
+
+// stdState 标准库中间件执行时的状态，通过请求的 context 传给中间件之后的 http.Handler
+type stdState struct {
+	ctx    *Context
+	called bool
+	err    error
+}
+
+// stdNext 标准库中间件的 next，继续执行 Context 中剩余的处理器
+var stdNext = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	state, ok := r.Context().Value(stdStateKey{}).(*stdState)
+	if !ok {
+		return
+	}
+	state.called = true
+	state.ctx.ResponseWriter = w
+	state.ctx.Request = r
+	state.err = state.ctx.Next()
+})
+
+// FromStd 将标准库风格的中间件 func(http.Handler) http.Handler 转为 Handler，例如：
+// app.Use(tsing.FromStd(cors.Default().Handler))
+// 中间件调用 next.ServeHTTP() 时继续执行之后的处理器，传给 next 的请求（例如使用 WithContext 替换后的请求）
+// 和 ResponseWriter 会同步到 Context；中间件没有调用 next 时，之后的处理器不会执行。
+// 之后的处理器返回的错误会在中间件返回后由 FromStd 返回，中间件返回后 Context 恢复原来的 ResponseWriter
+func FromStd(mw func(http.Handler) http.Handler) Handler {
+	h := mw(stdNext)
+	return func(ctx *Context) error {
+		state := &stdState{ctx: ctx}
+		writer := ctx.ResponseWriter
+		h.ServeHTTP(writer, ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), stdStateKey{}, state)))
+		ctx.ResponseWriter = writer
+		if !state.called {
+			ctx.index = len(ctx.handlers)
+		}
+		return state.err
+	}
+}
+
+// OriginalPath 获取请求被 Mount() 去掉路径前缀之前的原始路径，请求没有经过 Mount() 时返回当前路径
+func OriginalPath(req *http.Request) string {
+	if p, ok := req.Context().Value(originalPathKey{}).(string); ok {
+		return p
+	}
+	return req.URL.Path
+}
+
+// stripMountPrefix 复制请求并将路径替换为挂载前缀之后的部份 rest，原始路径保存在请求的 context 中，
+// 多次挂载时保留最外层的原始路径
+func stripMountPrefix(req *http.Request, rest string) *http.Request {
+	if rest == "" {
+		rest = "/"
+	}
+	if _, ok := req.Context().Value(originalPathKey{}).(string); ok {
+		r := *req
+		req = &r
+	} else {
+		req = req.WithContext(context.WithValue(req.Context(), originalPathKey{}, req.URL.Path))
+	}
+
+	u := *req.URL
+	u.Path = rest
+	u.RawPath = rawSuffix(req.URL.RawPath, rest)
+	req.URL = &u
+	return req
+}
+
+// rawSuffix 从编码后的路径 rawPath 中找出解码后等于 p 的后缀
+func rawSuffix(rawPath, p string) string {
+	if rawPath == "" {
+		return ""
+	}
+	for i := 0; i < len(rawPath); i++ {
+		if rawPath[i] != '/' {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(rawPath[i:]); err == nil && unescaped == p {
+			return rawPath[i:]
+		}
+	}
+	return ""
+}
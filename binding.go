@@ -0,0 +1,355 @@
+package tsing
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 绑定参数的来源，同时也是结构体字段的标签名，一个字段有多个来源时按此顺序查找
+const (
+	bindPath = iota
+	bindQuery
+	bindForm
+	bindHeader
+	bindCookie
+	bindSourceCount
+)
+
+var bindTags = [bindSourceCount]string{"path", "query", "form", "header", "cookie"}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+)
+
+// bindCache 缓存结构体类型的绑定信息 map[reflect.Type]*bindStruct
+var bindCache sync.Map
+
+// valuesGetter 按参数名获取某个来源的参数值
+type valuesGetter func(name string) ([]string, bool)
+
+// bindSources 绑定时各个来源的参数获取函数，为nil的来源会被忽略
+type bindSources struct {
+	values [bindSourceCount]valuesGetter
+	files  func(name string) ([]*multipart.FileHeader, bool) // form 来源中上传的文件
+}
+
+type bindSource struct {
+	source int
+	name   string
+}
+
+// bindField 结构体中需要绑定的字段
+type bindField struct {
+	index      []int
+	name       string
+	sources    []bindSource
+	defaults   []string // default 标签的值，切片字段按','分割
+	timeFormat string
+	file       bool // *multipart.FileHeader 或 []*multipart.FileHeader 类型的字段，只从上传的文件中绑定
+}
+
+// bindStruct 结构体的绑定信息
+type bindStruct struct {
+	fields  []bindField
+	sources [bindSourceCount]bool // 结构体中使用了哪些来源
+}
+
+// Bind 根据结构体字段的标签将路径参数、查询参数、表单参数、请求头和cookie绑定到 obj，obj 必须是结构体指针，例如：
+//
+//	type request struct {
+//		ID    int64     `path:"id"`
+//		Page  int       `query:"page" default:"1"`
+//		Tags  []string  `query:"tag"`
+//		Name  string    `form:"name"`
+//		Token string    `header:"X-Token"`
+//		SID   *string   `cookie:"sid"`
+//		Since time.Time `query:"since" time_format:"2006-01-02"`
+//	}
+//
+// 支持字符串、整数、浮点数、布尔值、time.Time（默认格式为RFC3339）、time.Duration、实现了 encoding.TextUnmarshaler 的类型，
+// 以及它们的切片和指针，上传的文件可以绑定到 form 标签的 *multipart.FileHeader 或 []*multipart.FileHeader 字段，
+// 没有标签的结构体字段会绑定其中的字段。参数不存在时使用 default 标签的值，否则保持字段原值。
+// 参数值转换失败时返回 *BindError，处理器返回该错误时响应400状态码。
+// Bind 不验证 obj，需要验证时使用 BindAndValidate 或在绑定之后调用 Validate
+func (ctx *Context) Bind(obj any) error {
+	rv, info, err := bindTarget(obj)
+	if err != nil {
+		return err
+	}
+
+	var sources bindSources
+	sources.values[bindPath] = func(name string) ([]string, bool) {
+		value, ok := ctx.PathParam(name)
+		return []string{value}, ok
+	}
+	sources.values[bindQuery] = ctx.QueryParams
+	if info.sources[bindForm] {
+		if err = ctx.formSources(&sources); err != nil {
+			return err
+		}
+	}
+	sources.values[bindHeader] = func(name string) ([]string, bool) {
+		values := ctx.Request.Header.Values(name)
+		return values, len(values) > 0
+	}
+	sources.values[bindCookie] = func(name string) ([]string, bool) {
+		var values []string
+		for _, cookie := range ctx.Request.Cookies() {
+			if cookie.Name == name {
+				values = append(values, cookie.Value)
+			}
+		}
+		return values, len(values) > 0
+	}
+	return bindValues(rv, info, &sources)
+}
+
+// formSources 解析表单并设置 form 来源的参数获取函数
+func (ctx *Context) formSources(sources *bindSources) error {
+	if err := ctx.InitFormCache(); err != nil {
+		return &BindError{Source: bindTags[bindForm], Err: err}
+	}
+	sources.values[bindForm] = ctx.FormParams
+	if form := ctx.Request.MultipartForm; form != nil {
+		sources.files = func(name string) ([]*multipart.FileHeader, bool) {
+			files, ok := form.File[name]
+			return files, ok
+		}
+	}
+	return nil
+}
+
+// bindTarget 检查绑定的目标并获取其绑定信息
+func bindTarget(obj any) (reflect.Value, *bindStruct, error) {
+	rv := reflect.ValueOf(obj)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return rv, nil, fmt.Errorf("binding target must be a non-nil pointer to a struct, got %T", obj)
+	}
+	rv = rv.Elem()
+	return rv, getBindStruct(rv.Type()), nil
+}
+
+// bindValues 从 sources 中获取参数值并绑定到结构体 rv
+func bindValues(rv reflect.Value, info *bindStruct, sources *bindSources) error {
+	for i := range info.fields {
+		field := &info.fields[i]
+		if field.file {
+			bindFile(rv.FieldByIndex(field.index), field, sources)
+			continue
+		}
+		var (
+			values []string
+			source bindSource
+			found  bool
+		)
+		for _, source = range field.sources {
+			if getter := sources.values[source.source]; getter != nil {
+				if values, found = getter(source.name); found {
+					break
+				}
+			}
+		}
+		if !found {
+			if field.defaults == nil {
+				continue
+			}
+			values = field.defaults
+		}
+
+		if err := setField(rv.FieldByIndex(field.index), values, field.timeFormat); err != nil {
+			return &BindError{
+				Field:  field.name,
+				Source: bindTags[source.source],
+				Name:   source.name,
+				Err:    err,
+			}
+		}
+	}
+	return nil
+}
+
+// bindFile 将上传的文件绑定到 *multipart.FileHeader 或 []*multipart.FileHeader 类型的字段
+func bindFile(v reflect.Value, field *bindField, sources *bindSources) {
+	if sources.files == nil {
+		return
+	}
+	for _, source := range field.sources {
+		if source.source != bindForm {
+			continue
+		}
+		if files, ok := sources.files(source.name); ok && len(files) > 0 {
+			if v.Kind() == reflect.Slice {
+				v.Set(reflect.ValueOf(files))
+			} else {
+				v.Set(reflect.ValueOf(files[0]))
+			}
+			return
+		}
+	}
+}
+
+// getBindStruct 获取结构体类型的绑定信息，解析结果会被缓存
+func getBindStruct(t reflect.Type) *bindStruct {
+	if info, ok := bindCache.Load(t); ok {
+		return info.(*bindStruct) //nolint:forcetypeassert
+	}
+	info := &bindStruct{}
+	parseBindFields(t, nil, "", info)
+	actual, _ := bindCache.LoadOrStore(t, info)
+	return actual.(*bindStruct) //nolint:forcetypeassert
+}
+
+func parseBindFields(t reflect.Type, index []int, prefix string, info *bindStruct) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() && !sf.Anonymous {
+			continue
+		}
+
+		field := bindField{
+			index:      append(index[:len(index):len(index)], i),
+			name:       prefix + sf.Name,
+			timeFormat: sf.Tag.Get("time_format"),
+		}
+		for source, tag := range bindTags {
+			if name := sf.Tag.Get(tag); name != "" && name != "-" {
+				field.sources = append(field.sources, bindSource{source: source, name: name})
+				info.sources[source] = true
+			}
+		}
+
+		// 没有标签的结构体字段，绑定其中的字段
+		if len(field.sources) == 0 {
+			if sf.Type.Kind() == reflect.Struct && sf.Type != timeType &&
+				!reflect.PointerTo(sf.Type).Implements(textUnmarshalerType) {
+				parseBindFields(sf.Type, field.index, field.name+".", info)
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if sf.Type == fileHeaderType || (sf.Type.Kind() == reflect.Slice && sf.Type.Elem() == fileHeaderType) {
+			field.file = true
+			info.fields = append(info.fields, field)
+			continue
+		}
+		if value, ok := sf.Tag.Lookup("default"); ok {
+			if sf.Type.Kind() == reflect.Slice && sf.Type.Elem().Kind() != reflect.Uint8 {
+				field.defaults = strings.Split(value, ",")
+			} else {
+				field.defaults = []string{value}
+			}
+		}
+		info.fields = append(info.fields, field)
+	}
+}
+
+// setField 将参数值写入字段，切片字段写入所有值，其它字段写入第一个值
+func setField(v reflect.Value, values []string, timeFormat string) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 &&
+		!reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), value, timeFormat); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return setValue(v, values[0], timeFormat)
+}
+
+// setValue 将字符串转换为字段的类型并写入，非字符串类型的空字符串会被忽略
+func setValue(v reflect.Value, value string, timeFormat string) error {
+	if v.Kind() == reflect.Pointer {
+		if value == "" && v.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), value, timeFormat); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	switch {
+	case v.Type() == timeType:
+		if value == "" {
+			return nil
+		}
+		if timeFormat == "" {
+			timeFormat = time.RFC3339
+		}
+		t, err := time.Parse(timeFormat, value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case v.Type() == durationType:
+		if value == "" {
+			return nil
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType):
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)) //nolint:forcetypeassert
+	}
+
+	if value == "" && v.Kind() != reflect.String && v.Kind() != reflect.Slice {
+		return nil
+	}
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice: // []byte
+		v.SetBytes([]byte(value))
+	default:
+		return errors.New("unsupported type " + v.Type().String())
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package tsing
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// paramConstraint 路径参数的约束，例如 /users/:id<int>、/files/:name<[a-z0-9-]+>
+type paramConstraint struct {
+	name  string            // 参数名
+	match func(string) bool // 检查参数值是否满足约束
+}
+
+// 内置的约束类型，其它的约束表达式都作为正则表达式处理
+var builtinConstraints = map[string]func(string) bool{
+	"int":   isInt,
+	"uint":  isUint,
+	"alpha": isAlpha,
+	"alnum": isAlnum,
+	"hex":   isHex,
+	"uuid":  isUUID,
+}
+
+// 已编译的正则表达式约束
+var constraintCache sync.Map
+
+// compileConstraint 编译约束表达式，正则表达式会自动添加'^'和'$'以匹配整个参数值
+func compileConstraint(expr string) (func(string) bool, error) {
+	if expr == "" {
+		return nil, errors.New("constraint can not be empty")
+	}
+	if match, exist := builtinConstraints[expr]; exist {
+		return match, nil
+	}
+	if v, exist := constraintCache.Load(expr); exist {
+		match, _ := v.(func(string) bool)
+		return match, nil
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, err
+	}
+	constraintCache.Store(expr, re.MatchString)
+	return re.MatchString, nil
+}
+
+// splitWildcard 将 :name<expr> 形式的参数拆分为参数名和约束表达式
+func splitWildcard(wildcard string) (name, expr string) {
+	name = wildcard[1:]
+	if i := strings.IndexByte(name, '<'); i >= 0 && name[len(name)-1] == '>' {
+		return name[:i], name[i+1 : len(name)-1]
+	}
+	return name, ""
+}
+
+// constraintEnd 返回约束表达式结尾'>'的位置，s 必须以'<'开头，表达式中可以嵌套成对的'<'和'>'
+// 约束表达式中不能包含'/'，未找到结尾时返回 -1
+func constraintEnd(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '<':
+			depth++
+		case '>':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case '/':
+			return -1
+		}
+	}
+	return -1
+}
+
+func isInt(s string) bool {
+	if s != "" && s[0] == '-' {
+		s = s[1:]
+	}
+	return isUint(s)
+}
+
+func isUint(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnum(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && !isAlpha(s[i:i+1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isHex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && (c|0x20 < 'a' || c|0x20 > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isUUID 检查是否为 8-4-4-4-12 格式的UUID
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if i == 8 || i == 13 || i == 18 || i == 23 {
+			if s[i] != '-' {
+				return false
+			}
+			continue
+		}
+		if !isHex(s[i : i+1]) {
+			return false
+		}
+	}
+	return true
+}
@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // Context is the most important part of gin. It allows us to pass variables between middleware,
@@ -21,38 +22,100 @@ type Context struct {
 	Status         int   // 处理器执行结果的状态码(HTTP)
 	Error          error // 处理器执行错误时的消息
 
+	writer       responseWriter
 	broke        bool
-	index        int8
+	index        int           // 当前执行的处理器在 handlers 中的索引
+	handlers     HandlersChain // 命中的路由的处理器链
 	fullPath     string
+	requestID    string
+	store        []storeEntry // 通过 Set() 写入的键值
+	route        *Route
 	engine       *Engine
 	params       *Params
+	hostParams   Params // 主机名参数
 	skippedNodes *[]skippedNode
 	queryCache   url.Values
 	formCache    url.Values
 }
 
+// Context 实现了 context.Context，可以直接传给数据库、RPC等需要 context.Context 的调用，
+// Deadline()、Done()、Err() 使用请求的 context，Value() 先查找通过 Set() 写入的键值。
+// Context 在请求结束后会被复用，在请求结束后仍在运行的goroutine中应使用 ctx.Request.Context()
+var _ context.Context = (*Context)(nil)
+
 func (ctx *Context) reset() {
 	ctx.Status = 200
 	ctx.Error = nil
 	ctx.index = -1
+	ctx.handlers = nil
 	ctx.broke = false
 	ctx.fullPath = ""
+	ctx.requestID = ""
+	clear(ctx.store)
+	ctx.store = ctx.store[:0]
+	ctx.route = nil
 	ctx.queryCache = nil
 	ctx.formCache = nil
 	*ctx.params = (*ctx.params)[:0]
+	ctx.hostParams = ctx.hostParams[:0]
 	*ctx.skippedNodes = (*ctx.skippedNodes)[:0]
 }
 
+// Deadline 返回请求的 context 的截止时间
+func (ctx *Context) Deadline() (deadline time.Time, ok bool) {
+	if ctx.Request == nil {
+		return
+	}
+	return ctx.Request.Context().Deadline()
+}
+
+// Done 返回请求的 context 的 Done 通道，客户端断开连接或请求超时时关闭
+func (ctx *Context) Done() <-chan struct{} {
+	if ctx.Request == nil {
+		return nil
+	}
+	return ctx.Request.Context().Done()
+}
+
+// Err 返回请求的 context 被取消的原因
+func (ctx *Context) Err() error {
+	if ctx.Request == nil {
+		return nil
+	}
+	return ctx.Request.Context().Err()
+}
+
 // EngineConfig 获取引擎配置
 func (ctx *Context) EngineConfig() Config {
 	return ctx.engine.config
 }
 
+// Writer 返回记录了响应状态的 ResponseWriter，可以获取已写入的状态码、字节数以及是否已写入响应头
+func (ctx *Context) Writer() ResponseWriter {
+	return &ctx.writer
+}
+
 // FullPath 返回路由注册时的路径
 func (ctx *Context) FullPath() string {
 	return ctx.fullPath
 }
 
+// RequestID 获取请求ID，需要由中间件（例如 middleware/requestid）通过 SetRequestID() 设置，
+// 可在 ErrorHandler 和 AfterHandler 中用于关联日志
+func (ctx *Context) RequestID() string {
+	return ctx.requestID
+}
+
+// SetRequestID 设置请求ID，保存在 Context 中，不会像 SetValue() 一样复制请求
+func (ctx *Context) SetRequestID(id string) {
+	ctx.requestID = id
+}
+
+// Route 返回命中的路由，可读取路由的元数据，路由未命中时返回nil
+func (ctx *Context) Route() *Route {
+	return ctx.route
+}
+
 // Abort 停止执行该路由注册的其它处理器
 func (ctx *Context) Abort() *Context {
 	ctx.broke = true
@@ -64,7 +127,32 @@ func (ctx *Context) IsAborted() bool {
 	return ctx.broke
 }
 
-// SetValue 在Context中写入键值，可用于在本次会话的处理器链中传递
+// Next 在中间件中执行处理器链中剩余的处理器，执行完成后返回，中间件可以在之后继续执行代码，例如：
+//
+//	app.Use(func(ctx *tsing.Context) error {
+//		start := time.Now()
+//		err := ctx.Next()
+//		log.Println(ctx.Request.URL.Path, time.Since(start))
+//		return err
+//	})
+//
+// 处理器返回错误时不再执行之后的处理器，并将该错误返回给调用 Next() 的中间件，中间件可以处理该错误并返回nil，
+// 也可以继续返回该错误交给 ErrorHandler 处理。中间件没有调用 Next() 时，之后的处理器在它返回后依次执行
+func (ctx *Context) Next() error {
+	for ctx.index++; ctx.index < len(ctx.handlers); ctx.index++ {
+		if ctx.broke {
+			return nil
+		}
+		if err := ctx.handlers[ctx.index](ctx); err != nil {
+			ctx.index = len(ctx.handlers)
+			return err
+		}
+	}
+	return nil
+}
+
+// SetValue 在请求的 context 中写入键值，可用于在本次会话的处理器链中传递，每次调用都会复制请求，
+// 只需要在 Context 中传递键值时应使用开销更小的 Set()
 func (ctx *Context) SetValue(key, value any) {
 	if key == nil {
 		return
@@ -72,22 +160,26 @@ func (ctx *Context) SetValue(key, value any) {
 	ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), key, value))
 }
 
-// GetValue 从Context中读取键值
+// GetValue 从Context中读取键值，与 Value() 相同
 func (ctx *Context) GetValue(key any) any {
 	if key == nil {
 		return nil
 	}
-	return ctx.Request.Context().Value(key)
+	return ctx.Value(key)
 }
 
-// PathValue 获取路径参数值
+// PathValue 获取路径参数值，路径中没有该参数时获取主机名参数值
 func (ctx *Context) PathValue(key string) string {
-	return ctx.params.ByName(key)
+	value, _ := ctx.PathParam(key)
+	return value
 }
 
-// PathParam 获取路径参数，并判断参数是否存在
+// PathParam 获取路径参数，并判断参数是否存在，路径中没有该参数时获取主机名参数
 func (ctx *Context) PathParam(key string) (string, bool) {
-	return ctx.params.Get(key)
+	if value, ok := ctx.params.Get(key); ok {
+		return value, true
+	}
+	return ctx.hostParams.Get(key)
 }
 
 // AllPathValues 获取所有路径参数值
@@ -251,6 +343,7 @@ func (ctx *Context) SaveFile(fileHeader *multipart.FileHeader, savePath string,
 // ServeFile 将服务端指定路径的文件写入到客户端流
 func (ctx *Context) ServeFile(filePath string) {
 	http.ServeFile(ctx.ResponseWriter, ctx.Request, filePath)
+	ctx.Status = ctx.writer.Status()
 }
 
 // FileFromFS 发布本地目录为静态文件目录
@@ -262,6 +355,7 @@ func (ctx *Context) FileFromFS(filepath string, fs http.FileSystem) {
 	ctx.Request.URL.Path = filepath
 
 	http.FileServer(fs).ServeHTTP(ctx.ResponseWriter, ctx.Request)
+	ctx.Status = ctx.writer.Status()
 }
 
 // Redirect 向客户端发送重定向响应
@@ -282,6 +376,7 @@ func (ctx *Context) String(status int, data string, charset ...string) (err erro
 	} else {
 		ctx.ResponseWriter.Header().Set("Content-Type", "text/plain; charset="+charset[0])
 	}
+	ctx.Status = status
 	ctx.ResponseWriter.WriteHeader(status)
 	_, err = ctx.ResponseWriter.Write(strToBytes(data))
 	return
@@ -299,6 +394,7 @@ func (ctx *Context) JSON(status int, data any, charset ...string) error {
 		contentType = "application/json; charset=" + charset[0]
 	}
 	ctx.ResponseWriter.Header().Set("Content-Type", contentType)
+	ctx.Status = status
 	ctx.ResponseWriter.WriteHeader(status)
 	_, err = ctx.ResponseWriter.Write(buf)
 	return err //nolint:wrapcheck
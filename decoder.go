@@ -0,0 +1,122 @@
+package tsing
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Decoder 请求体解码器，从 ctx.Request.Body 读取数据并解码到 obj
+type Decoder func(ctx *Context, obj any) error
+
+var (
+	decodersMu sync.RWMutex
+	// 媒体类型对应的请求体解码器
+	decoders = map[string]Decoder{
+		"application/json":                  decodeJSON,
+		"application/xml":                   decodeXML,
+		"text/xml":                          decodeXML,
+		"application/x-www-form-urlencoded": decodeForm,
+		"multipart/form-data":               decodeForm,
+	}
+)
+
+// RegisterDecoder 注册媒体类型 mediaType 的请求体解码器，已注册的媒体类型会被替换，例如：
+//
+//	tsing.RegisterDecoder("application/msgpack", func(ctx *tsing.Context, obj any) error {
+//		return msgpack.NewDecoder(ctx.Request.Body).Decode(obj)
+//	})
+func RegisterDecoder(mediaType string, decoder Decoder) {
+	if decoder == nil {
+		panic("decoder can not be nil")
+	}
+	decodersMu.Lock()
+	decoders[strings.ToLower(mediaType)] = decoder
+	decodersMu.Unlock()
+}
+
+// getDecoder 获取媒体类型的解码器，未注册的 "+json"、"+xml" 后缀的媒体类型使用JSON、XML解码器
+func getDecoder(mediaType string) (Decoder, bool) {
+	decodersMu.RLock()
+	decoder, ok := decoders[mediaType]
+	decodersMu.RUnlock()
+	if ok {
+		return decoder, true
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return decodeJSON, true
+	case strings.HasSuffix(mediaType, "+xml"):
+		return decodeXML, true
+	}
+	return nil, false
+}
+
+// BindBody 根据请求头 Content-Type 选择解码器，将请求体解码到 obj，
+// 内置JSON、XML、application/x-www-form-urlencoded 和 multipart/form-data 解码器，其它类型可以通过 RegisterDecoder 注册。
+// 表单使用 form 标签绑定到结构体，规则与 Bind 相同。请求体为空时 obj 保持原值。
+// 不支持的 Content-Type 返回 ErrUnsupportedMediaType（响应415状态码），解码失败时返回 *BindError（响应400状态码）
+func (ctx *Context) BindBody(obj any) error {
+	if ctx.Request.Body == nil || ctx.Request.Body == http.NoBody {
+		return nil
+	}
+	contentType := ctx.Request.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("%w: '%s'", ErrUnsupportedMediaType, contentType)
+	}
+	decoder, ok := getDecoder(mediaType)
+	if !ok {
+		return fmt.Errorf("%w: '%s'", ErrUnsupportedMediaType, mediaType)
+	}
+
+	if err = decoder(ctx, obj); err != nil {
+		var bindErr *BindError
+		if errors.As(err, &bindErr) || errors.Is(err, ErrUnsupportedMediaType) {
+			return err
+		}
+		return &BindError{Source: "body", Name: mediaType, Err: err}
+	}
+	return nil
+}
+
+// decodeJSON 使用 json.Decoder 流式解码请求体，根据 Config 设置 DisallowUnknownFields 和 UseNumber
+func decodeJSON(ctx *Context, obj any) error {
+	decoder := json.NewDecoder(ctx.Request.Body)
+	if ctx.engine.config.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if ctx.engine.config.UseNumber {
+		decoder.UseNumber()
+	}
+	if err := decoder.Decode(obj); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+func decodeXML(ctx *Context, obj any) error {
+	if err := xml.NewDecoder(ctx.Request.Body).Decode(obj); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// decodeForm 解析表单（包括上传的文件）并根据 form 标签绑定到结构体
+func decodeForm(ctx *Context, obj any) error {
+	rv, info, err := bindTarget(obj)
+	if err != nil {
+		return err
+	}
+	var sources bindSources
+	if err = ctx.formSources(&sources); err != nil {
+		return err
+	}
+	return bindValues(rv, info, &sources)
+}
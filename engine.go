@@ -3,9 +3,12 @@ package tsing
 import (
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // Config 引擎参数配置
@@ -13,18 +16,26 @@ type Config struct {
 	MaxMultipartMemory     int64           // 允许的请求Body大小(默认32 << 20 = 32MB)
 	Recovery               bool            // 自动恢复panic，防止进程退出
 	HandleMethodNotAllowed bool            // 不处理 405 错误（可以减少路由匹配时间），以 404 错误返回
+	RedirectTrailingSlash  bool            // 路由未命中但存在添加或删除末尾'/'的路由时，自动重定向到该路由
+	RedirectFixedPath      bool            // 路由未命中时清理路径中的'..'和'//'，并忽略大小写查找路由，找到则自动重定向
+	HandleOPTIONS          bool            // 未注册OPTIONS路由时自动响应OPTIONS请求，并在Allow头中列出路径允许的方法
+	OptionsHandler         CallbackHandler // 自动响应OPTIONS请求时的回调处理器，可用于处理CORS预检请求
 	ErrorHandler           CallbackHandler // 错误回调处理器
 	AfterHandler           CallbackHandler // 后置回调处理器，总是会在其它处理器全部执行完之后执行
+	DisallowUnknownFields  bool            // BindBody 解码JSON时，遇到结构体中不存在的字段返回错误
+	UseNumber              bool            // BindBody 解码JSON时，将数字解码为 json.Number 而不是 float64
+	Validator              Validator       // Validate 和 BindAndValidate 使用的验证器，为nil时使用内置的 validate 标签验证器
 }
 
 // Engine 引擎
 type Engine struct {
 	RouterGroup
 	config      Config
-	maxParams   int
-	maxSections int
 	contextPool sync.Pool
-	trees       methodTrees
+	table       atomic.Pointer[routeTable] // 当前使用的路由表
+	mu          sync.Mutex                 // 串行化路由表的修改和替换
+	serving     atomic.Bool                // 是否已经开始处理请求，之后注册路由时需要复制路由表
+	pre         HandlersChain              // 通过 Pre() 注册的前置中间件，最后一个是查找并执行路由的 dispatch
 }
 
 // Handler 路由处理器
@@ -36,6 +47,26 @@ type CallbackHandler func(*Context)
 // HandlersChain 处理器链
 type HandlersChain []Handler
 
+// RouteInfo 路由信息
+type RouteInfo struct {
+	Host         string         // 主机名，为空时表示默认路由
+	Method       string         // 请求方法
+	Path         string         // 路由注册时的完整路径
+	HandlerCount int            // 处理器数量（包含中间件）
+	HandlerNames []string       // 处理器的函数名称
+	Predicates   int            // 匹配条件的数量，为0时是路径的默认路由
+	Meta         map[string]any // 路由元数据
+}
+
+// RouteMatch 路由查找结果
+type RouteMatch struct {
+	Path     string        // 命中的路径，忽略大小写查找时为修正大小写后的路径
+	FullPath string        // 路由注册时的路径
+	Handlers HandlersChain // 路由处理器链
+	Params   Params        // 路径参数
+	Route    *Route        // 路由
+}
+
 // New 新建引擎实例
 func New(config ...Config) *Engine {
 	engine := &Engine{
@@ -44,9 +75,9 @@ func New(config ...Config) *Engine {
 			basePath: "/",
 			root:     true,
 		},
-		trees: make(methodTrees, 0, 9),
 	}
 	engine.RouterGroup.engine = engine
+	engine.table.Store(newRouteTable())
 
 	// 设置默认配置或使用提供的配置
 	if len(config) > 0 {
@@ -59,15 +90,16 @@ func New(config ...Config) *Engine {
 	}
 
 	engine.contextPool.New = func() any {
-		return engine.allocateContext(engine.maxParams)
+		table := engine.table.Load()
+		return engine.allocateContext(table.maxParams, table.maxSections)
 	}
 
 	return engine
 }
 
-func (engine *Engine) allocateContext(maxParams int) *Context {
+func (engine *Engine) allocateContext(maxParams, maxSections int) *Context {
 	v := make(Params, 0, maxParams)
-	skippedNodes := make([]skippedNode, 0, engine.maxSections)
+	skippedNodes := make([]skippedNode, 0, maxSections)
 	return &Context{
 		engine:       engine,
 		params:       &v,
@@ -75,124 +107,371 @@ func (engine *Engine) allocateContext(maxParams int) *Context {
 	}
 }
 
-func (engine *Engine) addRoute(method, path string, handlers HandlersChain) {
-	if path[0] != '/' {
-		log.Fatalln("路径必须以'/'开头")
+// Lookup 查找指定方法和路径的路由，caseInsensitive 为 true 时，精确匹配失败后会忽略大小写再查找一次
+// 只查找默认的路由，不包括通过 Host() 注册的主机名路由和通过 With() 注册的带有匹配条件的路由
+func (engine *Engine) Lookup(method, path string, caseInsensitive bool) (RouteMatch, bool) {
+	table := engine.table.Load()
+	root := table.trees.get(method)
+	if root == nil || path == "" || path[0] != '/' {
+		return RouteMatch{}, false
 	}
-	if method == "" {
-		log.Fatalln("方法不能为空")
+
+	params := make(Params, 0, table.maxParams)
+	skippedNodes := make([]skippedNode, 0, table.maxSections)
+	value := root.getValue(path, &params, &skippedNodes)
+
+	if value.handlers == nil && caseInsensitive {
+		fixedPath, ok := root.findCaseInsensitivePath(path, false)
+		if !ok {
+			return RouteMatch{}, false
+		}
+		path = bytesToStr(fixedPath)
+		params = params[:0]
+		skippedNodes = skippedNodes[:0]
+		value = root.getValue(path, &params, &skippedNodes)
 	}
-	if len(handlers) == 0 {
-		log.Fatalln("必须有至少一个处理器")
+
+	if value.handlers == nil || value.route == nil && len(value.routes) > 0 {
+		return RouteMatch{}, false
 	}
+	return RouteMatch{
+		Path:     path,
+		FullPath: value.fullPath,
+		Handlers: value.handlers,
+		Params:   params,
+		Route:    value.route,
+	}, true
+}
 
-	root := engine.trees.get(method)
-	if root == nil {
-		root = new(Node)
-		root.fullPath = "/"
-		engine.trees = append(engine.trees, methodTree{method: method, root: root})
+// URL 根据路由名称生成URL路径，参数值按照路由路径中参数出现的顺序传入，例如：
+// engine.GET("/users/:id/*action", handler).Name("user")
+// engine.URL("user", 1, "edit") 返回 "/users/1/edit"
+func (engine *Engine) URL(name string, params ...any) (string, error) {
+	route, exist := engine.table.Load().names[name]
+	if !exist {
+		return "", fmt.Errorf("route named '%s' not found", name)
 	}
-	root.addRoute(path, handlers)
+	return route.URL(params...)
+}
 
-	// 更新 maxParams
-	if paramsCount := countParams(path); paramsCount > engine.maxParams {
-		engine.maxParams = paramsCount
+// Routes 获取所有已注册的路由，按主机名、路径和方法排序
+func (engine *Engine) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0)
+	engine.table.Load().eachTree(func(host string, tree methodTree) {
+		tree.root.iterate(func(n *Node) {
+			if n.route != nil {
+				routes = append(routes, newRouteInfo(host, tree.method, n.fullPath, n.route))
+			}
+			for _, route := range n.routes {
+				routes = append(routes, newRouteInfo(host, tree.method, n.fullPath, route))
+			}
+		})
+	})
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Host != routes[j].Host {
+			return routes[i].Host < routes[j].Host
+		}
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+func newRouteInfo(host, method, fullPath string, route *Route) RouteInfo {
+	names := make([]string, len(route.handlers))
+	for i := range route.handlers {
+		names[i] = nameOfFunction(route.handlers[i])
 	}
+	return RouteInfo{
+		Host:         host,
+		Method:       method,
+		Path:         fullPath,
+		HandlerCount: len(route.handlers),
+		HandlerNames: names,
+		Predicates:   len(route.predicates),
+		Meta:         route.Meta,
+	}
+}
 
-	if sectionsCount := countSections(path); sectionsCount > engine.maxSections {
-		engine.maxSections = sectionsCount
+// Pre 注册前置中间件，在查找路由之前执行，未命中路由、405、自动响应OPTIONS、重定向以及panic的请求也会执行，
+// 适用于访问日志、请求ID等需要覆盖所有请求的中间件。中间件调用 ctx.Next() 时执行路由查找以及命中的路由的处理器，
+// 返回后可以通过 ctx.Writer().Status() 和 ctx.Error 获取处理结果。应在开始处理请求之前调用
+func (engine *Engine) Pre(handlers ...Handler) {
+	pre := engine.pre
+	if len(pre) > 0 {
+		pre = pre[:len(pre)-1]
 	}
+	engine.pre = append(append(pre[:len(pre):len(pre)], handlers...), engine.dispatch)
 }
 
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !engine.serving.Load() {
+		// 等待正在直接修改路由表的注册完成，之后的注册都会复制路由表
+		engine.mu.Lock()
+		engine.serving.Store(true)
+		engine.mu.Unlock()
+	}
 	ctx, _ := engine.contextPool.Get().(*Context)
-
 	ctx.Request = req
-	ctx.ResponseWriter = w
+	ctx.writer.reset(w)
+	ctx.ResponseWriter = &ctx.writer
 	ctx.reset()
 
+	if len(engine.pre) == 0 {
+		_ = engine.dispatch(ctx) //nolint:errcheck
+	} else {
+		// 处理前置中间件中的panic
+		if engine.config.Recovery {
+			defer engine.recoverPanic(ctx)
+		}
+		ctx.handlers = engine.pre
+		if err := ctx.Next(); err != nil {
+			handleError(ctx, engine, err, ErrorStatus(err))
+		}
+	}
+
+	engine.contextPool.Put(ctx)
+}
+
+// dispatch 查找并执行路由，作为前置中间件链的最后一个处理器，执行完成后恢复前置中间件链的状态
+func (engine *Engine) dispatch(ctx *Context) error {
+	handlers, index := ctx.handlers, ctx.index
+	defer func() {
+		ctx.handlers, ctx.index = handlers, index
+	}()
 	// 处理panic
 	if engine.config.Recovery {
-		defer func() {
-			if err := recover(); err != nil {
-				ctx.Status = http.StatusInternalServerError
-				ctx.Error = fmt.Errorf("%v", err)
-				if engine.config.ErrorHandler != nil {
-					engine.config.ErrorHandler(ctx)
-				} else {
-					ctx.ResponseWriter.WriteHeader(ctx.Status)
-					// _, _ = ctx.ResponseWriter.Write(strToBytes(ctx.Error.Error())) //nolint:errcheck
-				}
-			}
-		}()
+		defer engine.recoverPanic(ctx)
+	}
+
+	table := engine.table.Load()
+	// 路由表替换后，池中的Context预分配的容量可能不足
+	if cap(*ctx.params) < table.maxParams {
+		v := make(Params, 0, table.maxParams)
+		ctx.params = &v
+	}
+	if cap(*ctx.skippedNodes) < table.maxSections {
+		skippedNodes := make([]skippedNode, 0, table.maxSections)
+		ctx.skippedNodes = &skippedNodes
 	}
 
-	engine.handleRequest(ctx)
+	ctx.index = -1
+	engine.handleRequest(ctx, table)
+	return nil
+}
 
-	engine.contextPool.Put(ctx)
+// recoverPanic 恢复panic并交给 ErrorHandler 处理，必须通过 defer 调用
+func (engine *Engine) recoverPanic(ctx *Context) {
+	if err := recover(); err != nil {
+		ctx.Status = http.StatusInternalServerError
+		ctx.Error = fmt.Errorf("%v", err)
+		if engine.config.ErrorHandler != nil {
+			engine.config.ErrorHandler(ctx)
+		} else if !ctx.writer.Written() {
+			ctx.ResponseWriter.WriteHeader(ctx.Status)
+			// _, _ = ctx.ResponseWriter.Write(strToBytes(ctx.Error.Error())) //nolint:errcheck
+		}
+	}
 }
 
-func (engine *Engine) handleRequest(ctx *Context) {
+func (engine *Engine) handleRequest(ctx *Context, table *routeTable) {
 	var (
-		err  error
-		node nodeValue
+		hostRoot  *Node
+		hostValue nodeValue
+		hostTrees methodTrees
 	)
 
 	method := ctx.Request.Method
 	url := ctx.Request.URL.Path
-	t := engine.trees
 
-	// 在指定方法树中查找路径
-	for i := 0; i < len(t); i++ {
-		if t[i].method != method {
+	// 优先在与主机名匹配的路由树中查找
+	if host := table.matchHost(ctx.Request.Host, &ctx.hostParams); host != nil {
+		hostTrees = host.trees
+		hostRoot, hostValue = findRoute(ctx, hostTrees, method, url)
+		if hostValue.handlers != nil {
+			engine.execute(ctx, hostValue)
+			return
+		}
+		*ctx.params = (*ctx.params)[:0]
+		*ctx.skippedNodes = (*ctx.skippedNodes)[:0]
+		ctx.hostParams = ctx.hostParams[:0]
+	}
+
+	// 在默认的路由树中查找
+	root, node := findRoute(ctx, table.trees, method, url)
+	if node.handlers != nil {
+		engine.execute(ctx, node)
+		return
+	}
+
+	// 尝试修正路径并重定向
+	if method != http.MethodConnect && url != "/" {
+		if (hostValue.tsr || node.tsr) && engine.config.RedirectTrailingSlash {
+			redirectTrailingSlash(ctx)
+			return
+		}
+		if engine.config.RedirectFixedPath {
+			for _, r := range [2]*Node{hostRoot, root} {
+				if r != nil && redirectFixedPath(ctx, r, engine.config.RedirectTrailingSlash) {
+					return
+				}
+			}
+		}
+	}
+
+	// 自动响应 OPTIONS 请求
+	if method == http.MethodOptions && engine.config.HandleOPTIONS {
+		if allow := engine.allowed(url, method, ctx.skippedNodes, hostTrees, table.trees); allow != "" {
+			ctx.ResponseWriter.Header().Set("Allow", allow)
+			ctx.Status = http.StatusNoContent
+			if engine.config.OptionsHandler != nil {
+				engine.config.OptionsHandler(ctx)
+				return
+			}
+			ctx.ResponseWriter.WriteHeader(ctx.Status)
+			return
+		}
+	} else if engine.config.HandleMethodNotAllowed { // 处理 405 错误
+		if allow := engine.allowed(url, method, ctx.skippedNodes, hostTrees, table.trees); allow != "" {
+			ctx.ResponseWriter.Header().Set("Allow", allow)
+			handleError(ctx, engine, errors.New(http.StatusText(http.StatusMethodNotAllowed)), http.StatusMethodNotAllowed)
+			return
+		}
+	}
+
+	// 404 错误
+	handleError(ctx, engine, errors.New(http.StatusText(http.StatusNotFound)), http.StatusNotFound)
+}
+
+// 在指定方法的路由树中查找路径，返回方法树的根节点和查找结果
+func findRoute(ctx *Context, trees methodTrees, method, url string) (root *Node, value nodeValue) {
+	for i := 0; i < len(trees); i++ {
+		if trees[i].method != method {
 			continue
 		}
 
-		root := t[i].root
-		node = root.getValue(url, ctx.params, ctx.skippedNodes)
+		root = trees[i].root
+		value = root.getValue(url, ctx.params, ctx.skippedNodes)
 
 		// 如果存在路由参数
-		if node.params != nil {
-			ctx.params = node.params
+		if value.params != nil {
+			ctx.params = value.params
+		}
+
+		// 存在带有匹配条件的路由时，判断请求满足哪个路由的条件
+		if len(value.routes) > 0 && !value.matchRoute(ctx) {
+			value.handlers = nil
 		}
 		break
 	}
+	return root, value
+}
 
-	// 如果找到了处理器
-	if node.handlers != nil {
-		ctx.fullPath = node.fullPath
-		if engine.config.AfterHandler != nil {
-			defer engine.config.AfterHandler(ctx)
-		}
+// 执行命中的路由的处理器
+func (engine *Engine) execute(ctx *Context, value nodeValue) {
+	ctx.fullPath = value.fullPath
+	ctx.route = value.route
+	if engine.config.AfterHandler != nil {
+		defer engine.config.AfterHandler(ctx)
+	}
 
-		for _, handler := range node.handlers {
-			if ctx.broke {
-				break
-			}
-			if err = handler(ctx); err != nil {
-				handleError(ctx, engine, err, http.StatusInternalServerError)
-				return
-			}
-		}
-		return
+	ctx.handlers = value.handlers
+	if err := ctx.Next(); err != nil {
+		handleError(ctx, engine, err, ErrorStatus(err))
 	}
+}
 
-	// 处理 405 错误
-	if engine.config.HandleMethodNotAllowed {
-		for _, tree := range engine.trees {
-			if tree.method == method {
+// ErrorStatus 返回处理器返回的错误对应的HTTP状态码，*BindError 为400，ErrUnsupportedMediaType 为415，
+// ValidationErrors 为422，其它错误为500
+func ErrorStatus(err error) int {
+	var bindErr *BindError
+	if errors.As(err, &bindErr) {
+		return http.StatusBadRequest
+	}
+	var validationErrs ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return http.StatusUnprocessableEntity
+	}
+	if errors.Is(err, ErrUnsupportedMediaType) {
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusInternalServerError
+}
+
+// 获取路径允许的请求方法，多个方法以', '分隔，路径为'*'时返回所有已注册的方法
+func (engine *Engine) allowed(path, reqMethod string, skippedNodes *[]skippedNode, treesList ...methodTrees) string {
+	allowed := make([]string, 0, 10)
+	for _, trees := range treesList {
+		for _, tree := range trees {
+			// 自动响应 OPTIONS 请求时，OPTIONS 方法总是被允许的，在最后统一加入
+			if tree.method == reqMethod || (engine.config.HandleOPTIONS && tree.method == http.MethodOptions) {
 				continue
 			}
-			node = tree.root.getValue(url, nil, ctx.skippedNodes)
-			if node.handlers != nil {
-				handleError(ctx, engine, errors.New(http.StatusText(http.StatusMethodNotAllowed)), http.StatusMethodNotAllowed)
-				return
+			if containsString(allowed, tree.method) {
+				continue
+			}
+			if path != "*" {
+				*skippedNodes = (*skippedNodes)[:0]
+				// 只有带匹配条件的路由时，不满足条件的请求会是404，所以不计入允许的方法
+				value := tree.root.getValue(path, nil, skippedNodes)
+				if value.handlers == nil || value.route == nil && len(value.routes) > 0 {
+					continue
+				}
 			}
+			allowed = append(allowed, tree.method)
 		}
 	}
+	if len(allowed) == 0 {
+		return ""
+	}
 
-	// 404 错误
-	handleError(ctx, engine, errors.New(http.StatusText(http.StatusNotFound)), http.StatusNotFound)
+	if engine.config.HandleOPTIONS {
+		allowed = append(allowed, http.MethodOptions)
+	}
+	sort.Strings(allowed)
+	return strings.Join(allowed, ", ")
+}
+
+// 重定向到添加或删除末尾'/'之后的路径
+func redirectTrailingSlash(ctx *Context) {
+	p := ctx.Request.URL.Path
+	if length := len(p); length > 1 && p[length-1] == '/' {
+		p = p[:length-1]
+	} else {
+		p += "/"
+	}
+	redirectRequest(ctx, p)
+}
+
+// 清理路径并忽略大小写查找路由，如果找到则重定向到修正后的路径
+func redirectFixedPath(ctx *Context, root *Node, trailingSlash bool) bool {
+	fixedPath, ok := root.findCaseInsensitivePath(cleanPath(ctx.Request.URL.Path), trailingSlash)
+	if !ok {
+		return false
+	}
+	p := bytesToStr(fixedPath)
+	if p == ctx.Request.URL.Path {
+		return false
+	}
+	redirectRequest(ctx, p)
+	return true
+}
+
+// 重定向请求，GET和HEAD方法使用301状态码，其它方法使用308状态码以保留请求方法和Body
+func redirectRequest(ctx *Context, p string) {
+	code := http.StatusMovedPermanently
+	if ctx.Request.Method != http.MethodGet && ctx.Request.Method != http.MethodHead {
+		code = http.StatusPermanentRedirect
+	}
+	// 防止以'//'或'/\'开头的路径被客户端当作外部地址
+	if len(p) > 1 && (p[1] == '/' || p[1] == '\\') {
+		p = "/" + strings.TrimLeft(p, "/\\")
+	}
+	// 路径中的'?'、'#'等字符需要重新转义，否则会被客户端当作查询参数或片段
+	target := url.URL{Path: p, RawQuery: ctx.Request.URL.RawQuery}
+	_ = ctx.Redirect(code, target.String()) //nolint:errcheck
 }
 
 // 处理错误并执行错误处理器
@@ -205,7 +484,10 @@ func handleError(ctx *Context, engine *Engine, err error, status int) {
 		engine.config.ErrorHandler(ctx)
 		return
 	}
-	ctx.ResponseWriter.WriteHeader(ctx.Status)
+	// 处理器已经写入了响应头时不能再写入错误的状态码
+	if !ctx.writer.Written() {
+		ctx.ResponseWriter.WriteHeader(ctx.Status)
+	}
 	// if _, err = ctx.ResponseWriter.Write(strToBytes(ctx.Error.Error())); err != nil {
 	// }
 }
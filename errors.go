@@ -0,0 +1,46 @@
+package tsing
+
+import (
+	"errors"
+)
+
+var (
+	// ErrInvalidRoute 路由定义无效，例如路径不以'/'开头、参数名为空等
+	ErrInvalidRoute = errors.New("invalid route")
+	// ErrDuplicateRoute 路由已经注册过处理器
+	ErrDuplicateRoute = errors.New("handlers are already registered")
+	// ErrRouteNotFound 路由不存在
+	ErrRouteNotFound = errors.New("route not found")
+	// ErrUnsupportedMediaType 请求体的 Content-Type 没有注册解码器
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+)
+
+// ErrRouteConflict 路由与已注册路由的通配符冲突
+type ErrRouteConflict struct {
+	Path     string // 新注册的路由路径
+	Segment  string // 新路由中产生冲突的路径段
+	Existing string // 已注册路由中产生冲突的前缀
+}
+
+func (e *ErrRouteConflict) Error() string {
+	return "'" + e.Segment + "' in new path '" + e.Path + "' conflicts with existing prefix '" + e.Existing + "'"
+}
+
+// BindError 将请求参数绑定到结构体字段失败
+type BindError struct {
+	Field  string // 结构体字段名，嵌套结构体的字段以'.'连接
+	Source string // 参数来源，例如 path、query、form、header、cookie
+	Name   string // 参数名
+	Err    error  // 转换参数值时产生的错误
+}
+
+func (e *BindError) Error() string {
+	if e.Field == "" {
+		return "binding " + e.Source + " failed: " + e.Err.Error()
+	}
+	return "binding " + e.Source + " '" + e.Name + "' to field '" + e.Field + "' failed: " + e.Err.Error()
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
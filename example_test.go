@@ -1,10 +1,17 @@
 package tsing
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"log"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -261,3 +268,1435 @@ func TestCORS(t *testing.T) {
 	}
 	app.ServeHTTP(httptest.NewRecorder(), r)
 }
+
+// 测试自动重定向末尾'/'和修正路径
+func TestRedirect(t *testing.T) {
+	app := New(Config{
+		RedirectTrailingSlash: true,
+		RedirectFixedPath:     true,
+	})
+	app.GET("/users/", func(ctx *Context) error {
+		return nil
+	})
+	app.GET("/users/:id/profile", func(ctx *Context) error {
+		return nil
+	})
+	app.POST("/files/*filepath", func(ctx *Context) error {
+		return nil
+	})
+	app.GET("/über", func(ctx *Context) error {
+		return nil
+	})
+	app.GET("/docs/:name", func(ctx *Context) error {
+		return nil
+	})
+	// 路径参数可以匹配'\'、'?'等转义后的字符
+	pages := New(Config{RedirectTrailingSlash: true})
+	pages.GET("/:page/", func(ctx *Context) error {
+		return nil
+	})
+
+	type redirectCase struct {
+		method   string
+		path     string
+		code     int
+		location string
+	}
+	check := func(app *Engine, cases []redirectCase) {
+		for _, c := range cases {
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			r, err := http.NewRequestWithContext(ctx, c.method, c.path, nil)
+			if err != nil {
+				cancel()
+				t.Error(err)
+				return
+			}
+			resp := httptest.NewRecorder()
+			app.ServeHTTP(resp, r)
+			cancel()
+			if resp.Code != c.code || resp.Header().Get("Location") != c.location {
+				t.Errorf("%s %s: 期望 %d %q，实际 %d %q",
+					c.method, c.path, c.code, c.location, resp.Code, resp.Header().Get("Location"))
+			}
+		}
+	}
+
+	check(app, []redirectCase{
+		{http.MethodGet, "/users", http.StatusMovedPermanently, "/users/"},
+		{http.MethodGet, "/users?page=2", http.StatusMovedPermanently, "/users/?page=2"},
+		{http.MethodGet, "/USERS/", http.StatusMovedPermanently, "/users/"},
+		{http.MethodGet, "/Users/1/Profile", http.StatusMovedPermanently, "/users/1/profile"},
+		{http.MethodGet, "/users/1/profile/", http.StatusMovedPermanently, "/users/1/profile"},
+		{http.MethodGet, "/users/../users/1//profile", http.StatusMovedPermanently, "/users/1/profile"},
+		{http.MethodGet, "/ÜBER", http.StatusMovedPermanently, "/%C3%BCber"},
+		{http.MethodPost, "/FILES/a/B", http.StatusPermanentRedirect, "/files/a/B"},
+		{http.MethodGet, "/users/1", http.StatusNotFound, ""},
+		{http.MethodGet, "/users/1/profile", http.StatusOK, ""},
+		{http.MethodGet, "/DOCS/a%3Fx%23y", http.StatusMovedPermanently, "/docs/a%3Fx%23y"},
+	})
+	// 重定向地址需要重新转义，并且不能以'//'或'/\'开头
+	check(pages, []redirectCase{
+		{http.MethodGet, "/%5Cevil.com", http.StatusMovedPermanently, "/evil.com/"},
+		{http.MethodGet, "/%5C%5Cevil.com", http.StatusMovedPermanently, "/evil.com/"},
+		{http.MethodGet, "/a%3Fb", http.StatusMovedPermanently, "/a%3Fb/"},
+	})
+}
+
+// 测试查找路由
+func TestLookup(t *testing.T) {
+	app := New()
+	app.GET("/users/new", func(ctx *Context) error {
+		return nil
+	})
+	app.GET("/users/:id/Edit", func(ctx *Context) error {
+		return nil
+	})
+
+	if _, found := app.Lookup(http.MethodGet, "/USERS/NEW", false); found {
+		t.Error("区分大小写时不应命中 /USERS/NEW")
+	}
+	match, found := app.Lookup(http.MethodGet, "/USERS/NEW", true)
+	if !found || match.Path != "/users/new" || match.FullPath != "/users/new" {
+		t.Errorf("忽略大小写查找 /USERS/NEW 失败: %+v", match)
+	}
+	match, found = app.Lookup(http.MethodGet, "/Users/AbC/edit", true)
+	if !found || match.Path != "/users/AbC/Edit" || match.Params.ByName("id") != "AbC" {
+		t.Errorf("忽略大小写查找 /Users/AbC/edit 失败: %+v", match)
+	}
+	if _, found = app.Lookup(http.MethodPost, "/users/new", true); found {
+		t.Error("不应命中未注册的方法")
+	}
+	for _, path := range []string{"Xusers/new", "users/new"} {
+		if match, found = app.Lookup(http.MethodGet, path, true); found {
+			t.Errorf("不以'/'开头的路径 %s 不应命中: %+v", path, match)
+		}
+	}
+}
+
+// 测试 405 错误的 Allow 头以及自动响应 OPTIONS 请求
+func TestAllowHeader(t *testing.T) {
+	preflight := false
+	app := New(Config{
+		HandleMethodNotAllowed: true,
+		HandleOPTIONS:          true,
+		OptionsHandler: func(ctx *Context) {
+			preflight = true
+			ctx.ResponseWriter.Header().Set("Access-Control-Allow-Methods", ctx.ResponseWriter.Header().Get("Allow"))
+			ctx.ResponseWriter.WriteHeader(ctx.Status)
+		},
+	})
+	app.GET("/users/:id", func(ctx *Context) error {
+		return nil
+	})
+	app.DELETE("/users/:id", func(ctx *Context) error {
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	r, err := http.NewRequestWithContext(ctx, http.MethodPut, "/users/1", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, r)
+	if resp.Code != http.StatusMethodNotAllowed || resp.Header().Get("Allow") != "DELETE, GET, OPTIONS" {
+		t.Errorf("405 响应错误: %d %q", resp.Code, resp.Header().Get("Allow"))
+	}
+
+	r, err = http.NewRequestWithContext(ctx, http.MethodOptions, "/users/1", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	resp = httptest.NewRecorder()
+	app.ServeHTTP(resp, r)
+	if !preflight || resp.Code != http.StatusNoContent || resp.Header().Get("Access-Control-Allow-Methods") != "DELETE, GET, OPTIONS" {
+		t.Errorf("OPTIONS 响应错误: %d %q", resp.Code, resp.Header().Get("Access-Control-Allow-Methods"))
+	}
+}
+
+func listUsers(ctx *Context) error {
+	return nil
+}
+
+// 测试获取路由列表
+func TestRoutes(t *testing.T) {
+	app := New()
+	app.Use(func(ctx *Context) error {
+		return nil
+	})
+	app.GET("/users", listUsers)
+	app.Static("/static", "./", false)
+	app.Group("/users").POST("/:id/*action", listUsers)
+
+	routes := app.Routes()
+	for _, route := range routes {
+		t.Log(route.Method, route.Path, route.HandlerCount, route.HandlerNames)
+	}
+	if len(routes) != 4 {
+		t.Errorf("期望 4 个路由，实际 %d 个", len(routes))
+		return
+	}
+	if routes[0].Method != http.MethodGet || routes[0].Path != "/static/*filepath" || routes[0].HandlerCount != 2 {
+		t.Errorf("路由信息错误: %+v", routes[0])
+	}
+	last := routes[3]
+	if last.Path != "/users/:id/*action" || last.HandlerNames[1] != "github.com/dxvgef/tsing/v2.listUsers" {
+		t.Errorf("路由信息错误: %+v", last)
+	}
+}
+
+// 测试命名路由和反向生成URL
+func TestURL(t *testing.T) {
+	app := New()
+	api := app.Group("/api")
+	api.GET("/users/:id", listUsers).Name("user")
+	api.GET("/files/:owner/*filepath", listUsers).Name("file")
+
+	if u, err := app.URL("user", 10); err != nil || u != "/api/users/10" {
+		t.Errorf("生成URL错误: %q %v", u, err)
+	}
+	if u, err := app.URL("file", "a b", "/docs/中文.txt"); err != nil || u != "/api/files/a%20b/docs/%E4%B8%AD%E6%96%87.txt" {
+		t.Errorf("生成URL错误: %q %v", u, err)
+	}
+	if _, err := app.URL("user"); err == nil {
+		t.Error("缺少参数值时应返回错误")
+	}
+	if _, err := app.URL("user", 1, 2); err == nil {
+		t.Error("参数值过多时应返回错误")
+	}
+	if _, err := app.URL("none"); err == nil {
+		t.Error("路由名称不存在时应返回错误")
+	}
+}
+
+// 测试返回错误的路由注册
+func TestTryHandle(t *testing.T) {
+	app := New()
+	if _, err := app.TryHandle(http.MethodGet, "/users/:id", listUsers); err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err := app.TryHandle(http.MethodGet, "/users/:id", listUsers)
+	if !errors.Is(err, ErrDuplicateRoute) {
+		t.Errorf("期望 ErrDuplicateRoute，实际 %v", err)
+	}
+
+	_, err = app.TryHandle(http.MethodGet, "/users/:name/profile", listUsers)
+	var conflict *ErrRouteConflict
+	if !errors.As(err, &conflict) || conflict.Path != "/users/:name/profile" || conflict.Existing != "/users/:id" {
+		t.Errorf("期望 ErrRouteConflict，实际 %v", err)
+	}
+
+	for _, path := range []string{"/files/*", "/files/*path/more", "/a/:b:c", "/a/x*path"} {
+		if _, err = app.TryHandle(http.MethodGet, path, listUsers); !errors.Is(err, ErrInvalidRoute) {
+			t.Errorf("%s: 期望 ErrInvalidRoute，实际 %v", path, err)
+		}
+	}
+
+	// 失败的注册不能影响已注册的路由
+	if _, found := app.Lookup(http.MethodGet, "/users/1", false); !found {
+		t.Error("已注册的路由丢失")
+	}
+	if _, found := app.Lookup(http.MethodGet, "/a/x", false); found {
+		t.Error("失败的注册不应写入路由")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Handle 注册重复路由时应触发panic")
+		}
+	}()
+	app.GET("/users/:id", listUsers)
+}
+
+// 测试运行时替换路由表
+func TestReload(t *testing.T) {
+	app := New()
+	app.Use(func(ctx *Context) error {
+		ctx.ResponseWriter.Header().Set("X-Middleware", "ok")
+		return nil
+	})
+	app.GET("/old", listUsers)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		return resp
+	}
+
+	// 替换路由表的同时处理请求
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			serve("/old")
+			serve("/new/1/2/3")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if err := app.Reload(func(r Router) error {
+			r.GET("/old", listUsers)
+			r.Group("/new").GET("/:a/:b/:c", listUsers).Name("new")
+			return nil
+		}); err != nil {
+			t.Error(err)
+		}
+	}
+	<-done
+
+	if err := app.Reload(func(r Router) error {
+		r.GET("/new/:a/:b/:c", listUsers).Name("new")
+		return nil
+	}); err != nil {
+		t.Error(err)
+		return
+	}
+	if resp := serve("/old"); resp.Code != http.StatusNotFound {
+		t.Errorf("/old 应该已被删除，实际状态码 %d", resp.Code)
+	}
+	if resp := serve("/new/1/2/3"); resp.Code != http.StatusOK || resp.Header().Get("X-Middleware") != "ok" {
+		t.Errorf("/new 响应错误: %d %q", resp.Code, resp.Header().Get("X-Middleware"))
+	}
+	if u, err := app.URL("new", 1, 2, 3); err != nil || u != "/new/1/2/3" {
+		t.Errorf("生成URL错误: %q %v", u, err)
+	}
+
+	// 注册失败时保留当前的路由表
+	err := app.Reload(func(r Router) error {
+		r.GET("/broken", listUsers)
+		r.GET("/broken", listUsers)
+		return nil
+	})
+	if !errors.Is(err, ErrDuplicateRoute) {
+		t.Errorf("期望 ErrDuplicateRoute，实际 %v", err)
+	}
+	if resp := serve("/new/1/2/3"); resp.Code != http.StatusOK {
+		t.Errorf("注册失败后路由表不应被替换，实际状态码 %d", resp.Code)
+	}
+}
+
+// 测试删除路由
+func TestRemoveRoute(t *testing.T) {
+	app := New()
+	newUser := app.GET("/users/new", listUsers)
+	app.GET("/users/:id", listUsers).Name("user")
+	app.GET("/users/:id/posts/:pid", listUsers)
+	app.POST("/users", listUsers)
+
+	if err := app.RemoveRoute(http.MethodGet, "/users/:id/posts/:pid"); err != nil {
+		t.Error(err)
+	}
+	if err := app.RemoveRoute(http.MethodGet, "/users/:id"); err != nil {
+		t.Error(err)
+	}
+	if err := app.RemoveRoute(http.MethodGet, "/users/:id"); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("期望 ErrRouteNotFound，实际 %v", err)
+	}
+	// 删除失败之后设置的路由名称仍然写入当前的路由表
+	newUser.Name("newUser")
+	if u, err := app.URL("newUser"); err != nil || u != "/users/new" {
+		t.Errorf("期望 /users/new，实际 %q %v", u, err)
+	}
+	if err := app.RemoveRoute(http.MethodPost, "/users"); err != nil {
+		t.Error(err)
+	}
+
+	if _, found := app.Lookup(http.MethodGet, "/users/1", false); found {
+		t.Error("/users/:id 应该已被删除")
+	}
+	if _, found := app.Lookup(http.MethodGet, "/users/new", false); !found {
+		t.Error("/users/new 不应被删除")
+	}
+	if _, err := app.URL("user", 1); err == nil {
+		t.Error("已删除路由的名称应同时被删除")
+	}
+	routes := app.Routes()
+	if len(routes) != 1 || routes[0].Path != "/users/new" {
+		t.Errorf("删除后的路由列表错误: %+v", routes)
+	}
+
+	// 删除后可以重新注册
+	app.GET("/users/:id/posts/:pid", listUsers)
+	if match, found := app.Lookup(http.MethodGet, "/users/1/posts/2", false); !found || match.Params.ByName("pid") != "2" {
+		t.Errorf("重新注册的路由查找失败: %+v", match)
+	}
+}
+
+// 测试处理请求的同时注册、命名和删除路由
+func TestRegisterWhileServing(t *testing.T) {
+	app := New()
+	app.GET("/users", listUsers)
+	serve := func(path string) int {
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		return resp.Code
+	}
+	serve("/users")
+
+	started := make(chan struct{})
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				serve("/users")
+				serve("/items/" + strconv.Itoa(i%100))
+				_, _ = app.URL("item" + strconv.Itoa(i%100))
+			}
+			if i == 0 {
+				close(started)
+			}
+		}
+	}()
+	<-started
+	for i := 0; i < 100; i++ {
+		app.GET("/items/"+strconv.Itoa(i), listUsers).Name("item" + strconv.Itoa(i))
+		if i%2 == 1 {
+			if err := app.RemoveRoute(http.MethodGet, "/items/"+strconv.Itoa(i-1)); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+	close(stop)
+	<-done
+
+	for i := 0; i < 100; i++ {
+		expected := http.StatusOK
+		if i%2 == 0 {
+			expected = http.StatusNotFound
+		}
+		if code := serve("/items/" + strconv.Itoa(i)); code != expected {
+			t.Errorf("/items/%d: 期望 %d，实际 %d", i, expected, code)
+		}
+		u, err := app.URL("item" + strconv.Itoa(i))
+		if (err == nil) != (i%2 == 1) || err == nil && u != "/items/"+strconv.Itoa(i) {
+			t.Errorf("item%d: 生成URL错误 %q %v", i, u, err)
+		}
+	}
+}
+
+// 测试路由元数据
+func TestRouteMeta(t *testing.T) {
+	app := New()
+	admin := app.Group("/admin").SetMeta("scope", "admin")
+	admin.Use(func(ctx *Context) error {
+		if ctx.Route().Meta["scope"] != "admin" {
+			return errors.New("权限不足")
+		}
+		return nil
+	})
+	admin.GET("/users", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, ctx.Route().Meta["summary"].(string))
+	}).SetMeta("summary", "用户列表")
+
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/admin/users", nil))
+	if resp.Code != http.StatusOK || resp.Body.String() != "用户列表" {
+		t.Errorf("响应错误: %d %q", resp.Code, resp.Body.String())
+	}
+
+	routes := app.Routes()
+	if len(routes) != 1 || routes[0].Meta["scope"] != "admin" || routes[0].Meta["summary"] != "用户列表" {
+		t.Errorf("路由元数据错误: %+v", routes)
+	}
+
+	// 开始处理请求之后不能修改路由元数据
+	defer func() {
+		if recover() == nil {
+			t.Error("开始处理请求之后 SetMeta 应触发panic")
+		}
+	}()
+	admin.GET("/roles", listUsers).SetMeta("summary", "角色列表")
+}
+
+// 测试路径参数约束
+func TestParamConstraint(t *testing.T) {
+	app := New()
+	app.GET("/users/:id<int>", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "id="+ctx.PathValue("id"))
+	}).Name("user")
+	app.GET("/users/new", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "new")
+	})
+	app.GET("/files/:name<[a-z0-9-]+>/raw", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "name="+ctx.PathValue("name"))
+	})
+	app.GET("/orders/:uuid<uuid>", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "uuid="+ctx.PathValue("uuid"))
+	})
+	app.GET("/:page/x", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "page="+ctx.PathValue("page"))
+	})
+
+	cases := map[string]string{
+		"/users/42":      "id=42",
+		"/users/-1":      "id=-1",
+		"/users/new":     "new",
+		"/users/abc":     "",
+		"/users/newer":   "",
+		"/users/x":       "page=users",
+		"/files/a-1/raw": "name=a-1",
+		"/files/A_1/raw": "",
+		"/orders/abc":    "",
+		"/orders/0b6a2e3c-53ea-4bde-9b0e-7d1a7c4f2f11": "uuid=0b6a2e3c-53ea-4bde-9b0e-7d1a7c4f2f11",
+	}
+	for path, body := range cases {
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		if body == "" {
+			if resp.Code != http.StatusNotFound {
+				t.Errorf("%s: 期望 404，实际 %d", path, resp.Code)
+			}
+			continue
+		}
+		if resp.Code != http.StatusOK || resp.Body.String() != body {
+			t.Errorf("%s: 期望 %q，实际 %d %q", path, body, resp.Code, resp.Body.String())
+		}
+	}
+
+	if _, err := app.URL("user", "abc"); err == nil {
+		t.Error("参数值不满足约束时应返回错误")
+	}
+	// 同一位置不同约束的参数会产生冲突
+	var conflict *ErrRouteConflict
+	if _, err := app.TryHandle(http.MethodGet, "/users/:name<alpha>", listUsers); !errors.As(err, &conflict) {
+		t.Errorf("期望 *ErrRouteConflict，实际 %v", err)
+	}
+	for _, path := range []string{"/a/:id<int", "/a/:id<>", "/a/:id<[a-z>", "/a/*path<int>", "/a/:id<int>:x"} {
+		if _, err := app.TryHandle(http.MethodGet, path, listUsers); !errors.Is(err, ErrInvalidRoute) {
+			t.Errorf("%s: 期望 ErrInvalidRoute，实际 %v", path, err)
+		}
+	}
+}
+
+func TestMultiParamSegment(t *testing.T) {
+	app := New(Config{HandleMethodNotAllowed: true})
+	app.GET("/files/:name.:ext", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, ctx.PathValue("name")+"|"+ctx.PathValue("ext"))
+	}).Name("file")
+	app.GET("/docs/:id", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "doc="+ctx.PathValue("id"))
+	})
+	app.GET("/docs/:id.json", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "json="+ctx.PathValue("id"))
+	})
+	app.GET("/v1/api", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "v1")
+	})
+	app.GET("/v:version/api", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "version="+ctx.PathValue("version"))
+	})
+	app.GET("/@:user", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "user="+ctx.PathValue("user"))
+	})
+	app.GET("/range/:from~:to<int>/list", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, ctx.PathValue("from")+"~"+ctx.PathValue("to"))
+	})
+	// 参数值在静态文本处结束后查找失败时，回退到静态文本的下一个匹配位置，最后是路径段的结尾
+	app.GET("/json/:name.json", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "json="+ctx.PathValue("name"))
+	})
+	app.GET("/json/:name", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "name="+ctx.PathValue("name"))
+	})
+	app.GET("/f/:name.:ext", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, ctx.PathValue("name")+"|"+ctx.PathValue("ext"))
+	})
+	app.GET("/f/:name", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "name="+ctx.PathValue("name"))
+	})
+	// 其它方法的路由同样回退，以便返回405
+	app.GET("/p/:name.json", listUsers)
+	app.POST("/p/:name.json", listUsers)
+	app.POST("/p/:name", listUsers)
+
+	cases := map[string]string{
+		"/files/a.txt":       "a|txt",
+		"/files/a.tar.gz":    "a|tar.gz",
+		"/files/noext":       "",
+		"/docs/1":            "doc=1",
+		"/docs/1.json":       "json=1",
+		"/docs/a.b.json":     "json=a.b",
+		"/docs/a.b":          "doc=a.b",
+		"/v1/api":            "v1",
+		"/v2/api":            "version=2",
+		"/@tom":              "user=tom",
+		"/range/a~10/list":   "a~10",
+		"/range/a~b/list":    "",
+		"/range/a~b~10/list": "a~b~10",
+		"/range/a-b~10/list": "a-b~10",
+		"/json/a.json":       "json=a",
+		"/json/a.jsonx":      "name=a.jsonx",
+		"/json/a.json.json":  "json=a.json",
+		"/json/x.json.bak":   "name=x.json.bak",
+		"/f/abc.":            "name=abc.",
+		"/f/a.b.":            "a|b.",
+		"/p/a.jsonx":         "405",
+		"/p/a.json.bak":      "405",
+	}
+	for path, body := range cases {
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		if body == "" || body == "405" {
+			code := http.StatusNotFound
+			if body == "405" {
+				code = http.StatusMethodNotAllowed
+			}
+			if resp.Code != code {
+				t.Errorf("%s: 期望 %d，实际 %d", path, code, resp.Code)
+			}
+			continue
+		}
+		if resp.Code != http.StatusOK || resp.Body.String() != body {
+			t.Errorf("%s: 期望 %q，实际 %d %q", path, body, resp.Code, resp.Body.String())
+		}
+	}
+
+	// 忽略大小写查找时同样回退
+	if match, found := app.Lookup(http.MethodGet, "/JSON/a.JSON.bak", true); !found ||
+		match.Path != "/json/a.JSON.bak" || match.FullPath != "/json/:name" {
+		t.Errorf("期望 /json/:name，实际 %+v %v", match, found)
+	}
+	if u, err := app.URL("file", "report", "pdf"); err != nil || u != "/files/report.pdf" {
+		t.Errorf("期望 /files/report.pdf，实际 %s %v", u, err)
+	}
+	for _, path := range []string{"/x/:a:b", "/x/:a*b", "/x/:.ext"} {
+		if _, err := app.TryHandle(http.MethodGet, path, listUsers); !errors.Is(err, ErrInvalidRoute) {
+			t.Errorf("%s: 期望 ErrInvalidRoute，实际 %v", path, err)
+		}
+	}
+}
+
+func TestOptionalParam(t *testing.T) {
+	app := New()
+	app.GET("/archive/:year?/:month<int>?", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "year="+ctx.PathValue("year")+",month="+ctx.PathValue("month"))
+	}).Name("archive")
+	app.GET("/static/*filepath?", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "file="+ctx.PathValue("filepath"))
+	})
+
+	cases := map[string]string{
+		"/archive":          "year=,month=",
+		"/archive/2024":     "year=2024,month=",
+		"/archive/2024/05":  "year=2024,month=05",
+		"/archive/2024/may": "",
+		"/static":           "file=",
+		"/static/":          "file=/",
+		"/static/css/a.css": "file=/css/a.css",
+	}
+	for path, body := range cases {
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		if body == "" {
+			if resp.Code != http.StatusNotFound {
+				t.Errorf("%s: 期望 404，实际 %d", path, resp.Code)
+			}
+			continue
+		}
+		if resp.Code != http.StatusOK || resp.Body.String() != body {
+			t.Errorf("%s: 期望 %q，实际 %d %q", path, body, resp.Code, resp.Body.String())
+		}
+	}
+
+	urls := map[string][]any{
+		"/archive":         nil,
+		"/archive/2024":    {2024},
+		"/archive/2024/05": {2024, "05"},
+	}
+	for expected, params := range urls {
+		if u, err := app.URL("archive", params...); err != nil || u != expected {
+			t.Errorf("期望 %s，实际 %s %v", expected, u, err)
+		}
+	}
+
+	for _, path := range []string{"/a/:b?/c", "/a/:b?/:c", "/a/x:b?", "/a?b", "/a/:b?/"} {
+		if _, err := app.TryHandle(http.MethodGet, path, listUsers); !errors.Is(err, ErrInvalidRoute) {
+			t.Errorf("%s: 期望 ErrInvalidRoute，实际 %v", path, err)
+		}
+	}
+	// 展开后的路径冲突时不能残留已注册的部份
+	app.GET("/posts", listUsers)
+	if _, err := app.TryHandle(http.MethodGet, "/posts/:id?", listUsers); !errors.Is(err, ErrDuplicateRoute) {
+		t.Errorf("期望 ErrDuplicateRoute，实际 %v", err)
+	}
+	if _, err := app.TryHandle(http.MethodGet, "/posts/:id", listUsers); err != nil {
+		t.Error(err)
+	}
+	if err := app.RemoveRoute(http.MethodGet, "/archive/:year?/:month<int>?"); err != nil {
+		t.Error(err)
+	}
+	if len(app.Routes()) != 4 {
+		t.Errorf("期望剩余 4 个路由，实际 %v", app.Routes())
+	}
+
+	// 根路径的可选通配参数
+	root := New()
+	if _, err := root.TryHandle(http.MethodGet, "/*filepath?", listUsers); err != nil {
+		t.Error(err)
+	}
+	if match, found := root.Lookup(http.MethodGet, "/", false); !found || match.Params.ByName("filepath") != "/" {
+		t.Errorf("/*filepath? 应该匹配 /，实际 %+v", match)
+	}
+	if err := root.RemoveRoute(http.MethodGet, "/*filepath?"); err != nil || len(root.Routes()) != 0 {
+		t.Errorf("删除 /*filepath? 失败：%v %v", err, root.Routes())
+	}
+}
+
+func TestHost(t *testing.T) {
+	app := New(Config{HandleMethodNotAllowed: true})
+	app.GET("/index", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "default")
+	})
+	app.GET("/about", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "about")
+	})
+	api := app.Host("api.example.com")
+	api.GET("/index", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "api")
+	})
+	tenant := app.Host(":tenant.example.com").Group("/users")
+	tenant.GET("/:id", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, ctx.PathValue("tenant")+"/"+ctx.PathValue("id"))
+	})
+	tenant.POST("/new", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "new")
+	})
+	app.GET("/other", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "tenant="+ctx.PathValue("tenant"))
+	})
+
+	cases := []struct {
+		method, host, path string
+		code               int
+		body               string
+	}{
+		{http.MethodGet, "api.example.com", "/index", http.StatusOK, "api"},
+		{http.MethodGet, "API.Example.com:8080", "/index", http.StatusOK, "api"},
+		{http.MethodGet, "api.example.com", "/about", http.StatusOK, "about"},
+		{http.MethodGet, "www.example.com", "/index", http.StatusOK, "default"},
+		{http.MethodGet, "acme.example.com", "/users/1", http.StatusOK, "acme/1"},
+		{http.MethodGet, "a.b.example.com", "/users/1", http.StatusNotFound, ""},
+		{http.MethodGet, "localhost", "/users/1", http.StatusNotFound, ""},
+		{http.MethodGet, "acme.example.com", "/users/new", http.StatusOK, "acme/new"},
+		{http.MethodDelete, "acme.example.com", "/users/new", http.StatusMethodNotAllowed, ""},
+		// 回退到默认的路由时不能残留主机名参数
+		{http.MethodGet, "acme.example.com", "/other", http.StatusOK, "tenant="},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		req.Host = c.host
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, req)
+		if resp.Code != c.code || (c.body != "" && resp.Body.String() != c.body) {
+			t.Errorf("%s %s%s: 期望 %d %q，实际 %d %q", c.method, c.host, c.path, c.code, c.body, resp.Code, resp.Body.String())
+		}
+	}
+
+	hosts := make(map[string]int)
+	for _, route := range app.Routes() {
+		hosts[route.Host]++
+	}
+	if hosts[""] != 3 || hosts["api.example.com"] != 1 || hosts[":tenant.example.com"] != 2 {
+		t.Errorf("路由列表错误：%v", hosts)
+	}
+	if _, err := app.Host("a..com").TryHandle(http.MethodGet, "/", listUsers); !errors.Is(err, ErrInvalidRoute) {
+		t.Errorf("期望 ErrInvalidRoute，实际 %v", err)
+	}
+
+	// 删除主机名路由
+	if err := app.RemoveRoute(http.MethodGet, "/users/:id"); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("RemoveRoute 不应删除主机名路由，实际 %v", err)
+	}
+	if err := app.RemoveHostRoute("API.example.com", http.MethodGet, "/index"); err != nil {
+		t.Error(err)
+	}
+	if err := app.RemoveHostRoute("api.example.com", http.MethodGet, "/index"); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("期望 ErrRouteNotFound，实际 %v", err)
+	}
+	if err := app.RemoveHostRoute(":tenant.example.com", http.MethodGet, "/users/:id"); err != nil {
+		t.Error(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/index", nil)
+	req.Host = "api.example.com"
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, req)
+	if resp.Body.String() != "default" {
+		t.Errorf("删除主机名路由后期望回退到默认路由，实际 %q", resp.Body.String())
+	}
+	routes := app.Routes()
+	if len(routes) != 4 || routes[3].Host != ":tenant.example.com" || routes[3].Method != http.MethodPost {
+		t.Errorf("删除后的路由列表错误：%+v", routes)
+	}
+}
+
+func TestPredicate(t *testing.T) {
+	app := New(Config{HandleMethodNotAllowed: true})
+	app.GET("/users", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "v1")
+	})
+	app.With(HeaderIs("X-API-Version", "2")).GET("/users", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "v2")
+	})
+	app.With(Accepts("text/csv")).GET("/users", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "csv")
+	})
+	debug := app.Group("/debug").With(QueryIs("debug", "1"))
+	vars := debug.GET("/vars", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "vars")
+	})
+
+	cases := []struct {
+		path, header, value string
+		code                int
+		body                string
+	}{
+		{"/users", "", "", http.StatusOK, "v1"},
+		{"/users", "X-API-Version", "2", http.StatusOK, "v2"},
+		{"/users", "X-API-Version", "3", http.StatusOK, "v1"},
+		{"/users", "Accept", "text/html, text/*;q=0.8", http.StatusOK, "csv"},
+		{"/users", "Accept", "*/*", http.StatusOK, "v1"},
+		{"/debug/vars?debug=1", "", "", http.StatusOK, "vars"},
+		{"/debug/vars", "", "", http.StatusNotFound, ""},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		if c.header != "" {
+			req.Header.Set(c.header, c.value)
+		}
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, req)
+		if resp.Code != c.code || (c.body != "" && resp.Body.String() != c.body) {
+			t.Errorf("%s %s: 期望 %d %q，实际 %d %q", c.path, c.value, c.code, c.body, resp.Code, resp.Body.String())
+		}
+	}
+
+	if _, err := app.TryHandle(http.MethodGet, "/users", listUsers); !errors.Is(err, ErrDuplicateRoute) {
+		t.Errorf("期望 ErrDuplicateRoute，实际 %v", err)
+	}
+	// 只有带匹配条件的路由的方法不计入 Allow
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/debug/vars", nil))
+	if resp.Code != http.StatusNotFound {
+		t.Errorf("期望 404，实际 %d %q", resp.Code, resp.Header().Get("Allow"))
+	}
+	if routes := app.Routes(); len(routes) != 4 || routes[0].Predicates != 1 || routes[1].Predicates != 0 {
+		t.Errorf("路由列表错误：%v", routes)
+	}
+
+	// 删除其它路由之后，带有匹配条件的路由设置的名称写入新的路由表
+	app.GET("/tmp", listUsers)
+	if err := app.RemoveRoute(http.MethodGet, "/tmp"); err != nil {
+		t.Error(err)
+	}
+	vars.Name("vars")
+	if u, err := app.URL("vars"); err != nil || u != "/debug/vars" {
+		t.Errorf("期望 /debug/vars，实际 %q %v", u, err)
+	}
+
+	// 删除默认路由后保留带有匹配条件的路由
+	if err := app.RemoveRoute(http.MethodGet, "/users"); err != nil {
+		t.Error(err)
+	}
+	if err := app.RemoveRoute(http.MethodGet, "/users"); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("期望 ErrRouteNotFound，实际 %v", err)
+	}
+	if routes := app.Routes(); len(routes) != 3 {
+		t.Errorf("路由列表错误：%v", routes)
+	}
+	for _, c := range []struct{ header, value, body string }{
+		{"X-API-Version", "2", "v2"},
+		{"Accept", "text/csv", "csv"},
+		{"", "", ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		if c.header != "" {
+			req.Header.Set(c.header, c.value)
+		}
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, req)
+		if c.body == "" && resp.Code != http.StatusNotFound || c.body != "" && resp.Body.String() != c.body {
+			t.Errorf("%s: 期望 %q，实际 %d %q", c.value, c.body, resp.Code, resp.Body.String())
+		}
+	}
+
+	// 删除单个带有匹配条件的路由
+	if err := vars.Remove(); err != nil {
+		t.Error(err)
+	}
+	if err := vars.Remove(); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("期望 ErrRouteNotFound，实际 %v", err)
+	}
+	if _, err := app.URL("vars"); err == nil {
+		t.Error("期望删除的路由名称不可用")
+	}
+	resp = httptest.NewRecorder()
+	app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/debug/vars?debug=1", nil))
+	if resp.Code != http.StatusNotFound {
+		t.Errorf("期望 404，实际 %d", resp.Code)
+	}
+	if routes := app.Routes(); len(routes) != 2 {
+		t.Errorf("路由列表错误：%v", routes)
+	}
+}
+
+func TestMount(t *testing.T) {
+	sub := New()
+	sub.GET("/users/:id", func(ctx *Context) error {
+		return ctx.String(http.StatusOK, "sub:"+ctx.PathValue("id")+":"+OriginalPath(ctx.Request))
+	})
+
+	app := New()
+	admin := app.Group("/admin", func(ctx *Context) error {
+		ctx.ResponseWriter.Header().Set("X-Admin", "1")
+		return nil
+	})
+	admin.Mount("/api", sub)
+	app.Mount("/raw", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path + " " + OriginalPath(r))) //nolint:errcheck
+	}))
+	app.GET("/health", WrapHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok")) //nolint:errcheck
+	}))
+
+	cases := []struct {
+		method, path, body string
+	}{
+		{http.MethodGet, "/admin/api/users/1", "sub:1:/admin/api/users/1"},
+		{http.MethodPost, "/raw/a/b", "POST /a/b /raw/a/b"},
+		{http.MethodDelete, "/raw", "DELETE / /raw"},
+		{http.MethodGet, "/health", "ok"},
+	}
+	for _, c := range cases {
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, httptest.NewRequest(c.method, c.path, nil))
+		if resp.Code != http.StatusOK || resp.Body.String() != c.body {
+			t.Errorf("%s %s: 期望 %q，实际 %d %q", c.method, c.path, c.body, resp.Code, resp.Body.String())
+		}
+	}
+
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/admin/api/users/2", nil))
+	if resp.Header().Get("X-Admin") != "1" {
+		t.Error("路由组的中间件未执行")
+	}
+
+	// 挂载到根路径
+	root := New()
+	root.Mount("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path)) //nolint:errcheck
+	}))
+	for path, body := range map[string]string{"/users/3": "/users/3", "/": "/"} {
+		resp = httptest.NewRecorder()
+		root.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		if resp.Body.String() != body {
+			t.Errorf("%s: 期望 %q，实际 %d %q", path, body, resp.Code, resp.Body.String())
+		}
+	}
+}
+
+type upperWriter struct {
+	http.ResponseWriter
+}
+
+func (w upperWriter) Write(p []byte) (int, error) {
+	return w.ResponseWriter.Write(bytes.ToUpper(p))
+}
+
+type userKey struct{}
+
+func TestFromStd(t *testing.T) {
+	wrap := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Std", "1")
+			next.ServeHTTP(upperWriter{w}, r.WithContext(context.WithValue(r.Context(), userKey{}, "tom")))
+		})
+	}
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("token") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	app := New()
+	app.Use(FromStd(wrap), FromStd(deny))
+	app.GET("/user", func(ctx *Context) error {
+		user, _ := ctx.Request.Context().Value(userKey{}).(string)
+		return ctx.String(http.StatusOK, "hello "+user)
+	})
+	app.GET("/error", func(ctx *Context) error {
+		return errors.New("failed")
+	})
+
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/user?token=1", nil))
+	if resp.Code != http.StatusOK || resp.Body.String() != "HELLO TOM" || resp.Header().Get("X-Std") != "1" {
+		t.Errorf("期望 200 \"HELLO TOM\"，实际 %d %q", resp.Code, resp.Body.String())
+	}
+
+	resp = httptest.NewRecorder()
+	app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/user", nil))
+	if resp.Code != http.StatusUnauthorized || resp.Body.Len() != 0 {
+		t.Errorf("期望 401，实际 %d %q", resp.Code, resp.Body.String())
+	}
+
+	resp = httptest.NewRecorder()
+	app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/error?token=1", nil))
+	if resp.Code != http.StatusInternalServerError {
+		t.Errorf("期望 500，实际 %d", resp.Code)
+	}
+}
+
+func TestNext(t *testing.T) {
+	var steps []string
+	app := New()
+	app.Use(func(ctx *Context) error {
+		steps = append(steps, "a:before")
+		err := ctx.Next()
+		steps = append(steps, "a:after")
+		return err
+	}, func(ctx *Context) error {
+		steps = append(steps, "b")
+		return nil
+	}, func(ctx *Context) error {
+		if err := ctx.Next(); err != nil {
+			steps = append(steps, "c:recover")
+			return ctx.String(http.StatusTeapot, err.Error())
+		}
+		return nil
+	})
+	app.GET("/ok", func(ctx *Context) error {
+		steps = append(steps, "handler")
+		return ctx.String(http.StatusOK, "ok")
+	})
+	app.GET("/error", func(ctx *Context) error {
+		steps = append(steps, "handler")
+		return errors.New("failed")
+	})
+
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	if strings.Join(steps, ",") != "a:before,b,handler,a:after" {
+		t.Errorf("执行顺序错误：%v", steps)
+	}
+
+	steps = nil
+	resp = httptest.NewRecorder()
+	app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/error", nil))
+	if strings.Join(steps, ",") != "a:before,b,handler,c:recover,a:after" {
+		t.Errorf("执行顺序错误：%v", steps)
+	}
+	if resp.Code != http.StatusTeapot || resp.Body.String() != "failed" {
+		t.Errorf("期望 418 \"failed\"，实际 %d %q", resp.Code, resp.Body.String())
+	}
+}
+
+// 测试前置中间件
+func TestPre(t *testing.T) {
+	var steps []string
+	app := New(Config{Recovery: true})
+	app.Pre(func(ctx *Context) error {
+		steps = append(steps, "pre:before")
+		err := ctx.Next()
+		steps = append(steps, "pre:after:"+strconv.Itoa(ctx.Writer().Status()))
+		return err
+	})
+	app.Pre(func(ctx *Context) error {
+		if ctx.Request.Header.Get("X-Token") == "" {
+			return &BindError{Source: "header", Name: "X-Token", Err: errors.New("missing")}
+		}
+		return nil
+	})
+	app.Use(func(ctx *Context) error {
+		steps = append(steps, "use")
+		return nil
+	})
+	app.GET("/ok", func(ctx *Context) error {
+		steps = append(steps, "handler")
+		return ctx.String(http.StatusOK, "ok")
+	})
+	app.GET("/panic", func(ctx *Context) error {
+		panic("boom")
+	})
+
+	cases := []struct {
+		path, token string
+		code        int
+		steps       string
+	}{
+		{"/ok", "1", http.StatusOK, "pre:before,use,handler,pre:after:200"},
+		{"/none", "1", http.StatusNotFound, "pre:before,pre:after:404"},
+		{"/panic", "1", http.StatusInternalServerError, "pre:before,use,pre:after:500"},
+		{"/ok", "", http.StatusBadRequest, "pre:before,pre:after:200"},
+	}
+	for _, c := range cases {
+		steps = nil
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		if c.token != "" {
+			req.Header.Set("X-Token", c.token)
+		}
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, req)
+		if resp.Code != c.code || strings.Join(steps, ",") != c.steps {
+			t.Errorf("%s: 期望 %d %s，实际 %d %v", c.path, c.code, c.steps, resp.Code, steps)
+		}
+	}
+}
+
+func TestResponseWriter(t *testing.T) {
+	var status, size int
+	app := New(Config{
+		AfterHandler: func(ctx *Context) {
+			status, size = ctx.Writer().Status(), ctx.Writer().Size()
+		},
+	})
+	app.GET("/created", func(ctx *Context) error {
+		if ctx.Writer().Written() {
+			t.Error("写入响应前 Written() 应为 false")
+		}
+		return ctx.String(http.StatusCreated, "hello")
+	})
+	app.GET("/error", func(ctx *Context) error {
+		_ = ctx.String(http.StatusOK, "partial") //nolint:errcheck
+		return errors.New("failed")
+	})
+	app.GET("/flush", func(ctx *Context) error {
+		_, _ = ctx.ResponseWriter.Write([]byte("chunk")) //nolint:errcheck
+		return http.NewResponseController(ctx.ResponseWriter).Flush()
+	})
+
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/created", nil))
+	if resp.Code != http.StatusCreated || status != http.StatusCreated || size != 5 {
+		t.Errorf("期望 201 5，实际 %d %d %d", resp.Code, status, size)
+	}
+
+	// 处理器已经写入响应后返回错误，不能再写入500状态码
+	resp = httptest.NewRecorder()
+	app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/error", nil))
+	if resp.Code != http.StatusOK || status != http.StatusOK || resp.Body.String() != "partial" {
+		t.Errorf("期望 200 \"partial\"，实际 %d %d %q", resp.Code, status, resp.Body.String())
+	}
+
+	resp = httptest.NewRecorder()
+	app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/flush", nil))
+	if !resp.Flushed || size != 5 {
+		t.Errorf("期望已 Flush 并写入 5 字节，实际 %v %d", resp.Flushed, size)
+	}
+}
+
+func TestStore(t *testing.T) {
+	type user struct{ name string }
+	app := New()
+	app.Use(func(ctx *Context) error {
+		if _, exists := ctx.Get("user"); exists {
+			t.Error("上一个请求写入的键值未清空")
+		}
+		ctx.Set("user", &user{name: "tom"})
+		ctx.Set("count", 1)
+		ctx.Set("count", 2)
+		ctx.Set(userKey{}, "typed key")
+		ctx.SetValue("legacy", "request")
+		return nil
+	})
+	app.GET("/store", func(ctx *Context) error {
+		u, ok := Get[*user](ctx, "user")
+		if !ok || u.name != "tom" || ctx.MustGet("user").(*user) != u {
+			t.Errorf("读取键值错误：%v %v", u, ok)
+		}
+		if _, ok = Get[string](ctx, "user"); ok {
+			t.Error("类型不匹配时应返回 false")
+		}
+		if ctx.GetInt("count") != 2 || ctx.GetString("count") != "" {
+			t.Errorf("期望 count=2，实际 %v", ctx.GetInt("count"))
+		}
+		if ctx.Value(userKey{}) != "typed key" || ctx.Value("legacy") != "request" || ctx.GetValue("count") != 2 {
+			t.Error("Value() 读取键值错误")
+		}
+		return ctx.NoContent()
+	})
+
+	for i := 0; i < 2; i++ {
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/store", nil))
+		if resp.Code != http.StatusNoContent {
+			t.Errorf("期望 204，实际 %d", resp.Code)
+		}
+	}
+}
+
+func TestContextContext(t *testing.T) {
+	lookup := func(c context.Context, key any) any {
+		return c.Value(key)
+	}
+	app := New()
+	app.GET("/ctx", func(ctx *Context) error {
+		ctx.Set("user", "tom")
+		if lookup(ctx, "user") != "tom" || lookup(ctx, userKey{}) != "request" {
+			t.Error("context.Context.Value() 读取键值错误")
+		}
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("期望使用请求的 context 的截止时间")
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	c, cancel := context.WithTimeout(context.WithValue(context.Background(), userKey{}, "request"), time.Millisecond)
+	defer cancel()
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/ctx", nil).WithContext(c))
+	if resp.Code != http.StatusInternalServerError {
+		t.Errorf("期望 context 超时后返回 500，实际 %d", resp.Code)
+	}
+}
+
+func TestBind(t *testing.T) {
+	type page struct {
+		Page int `query:"page" default:"1"`
+		Size int `query:"size" default:"20"`
+	}
+	type request struct {
+		page
+		ID      int64         `path:"id"`
+		Tags    []string      `query:"tag"`
+		Sort    []string      `query:"sort" default:"id,name"`
+		Name    string        `form:"name" query:"name"`
+		Token   string        `header:"X-Token"`
+		SID     *string       `cookie:"sid"`
+		Since   time.Time     `query:"since" time_format:"2006-01-02"`
+		Timeout time.Duration `query:"timeout"`
+		IP      net.IP        `header:"X-Real-IP"`
+		Filter  struct {
+			Active *bool `query:"active"`
+		}
+	}
+
+	app := New()
+	app.POST("/users/:id", func(ctx *Context) error {
+		var req request
+		if err := ctx.Bind(&req); err != nil {
+			return err
+		}
+		if req.ID != 10 || req.Page != 2 || req.Size != 20 || len(req.Tags) != 2 || req.Tags[1] != "b" {
+			t.Errorf("绑定路径参数或查询参数错误：%+v", req)
+		}
+		if len(req.Sort) != 2 || req.Sort[1] != "name" || req.Name != "tom" || req.Token != "abc" {
+			t.Errorf("绑定默认值、表单或请求头错误：%+v", req)
+		}
+		if req.SID == nil || *req.SID != "s1" || req.Filter.Active == nil || !*req.Filter.Active {
+			t.Errorf("绑定cookie或嵌套结构体错误：%+v", req)
+		}
+		if req.Since.Day() != 2 || req.Timeout != 3*time.Second || !req.IP.Equal(net.IPv4(10, 0, 0, 1)) {
+			t.Errorf("绑定时间或 TextUnmarshaler 错误：%+v", req)
+		}
+		return ctx.NoContent()
+	})
+
+	query := "?page=2&tag=a&tag=b&since=2024-01-02&timeout=3s&active=true"
+	req := httptest.NewRequest(http.MethodPost, "/users/10"+query, strings.NewReader("name=tom"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Token", "abc")
+	req.Header.Set("X-Real-IP", "10.0.0.1")
+	req.AddCookie(&http.Cookie{Name: "sid", Value: "s1"})
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, req)
+	if resp.Code != http.StatusNoContent {
+		t.Errorf("期望 204，实际 %d", resp.Code)
+	}
+
+	// 参数值无法转换时响应400
+	resp = httptest.NewRecorder()
+	app.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/users/x", nil))
+	if resp.Code != http.StatusBadRequest {
+		t.Errorf("期望 400，实际 %d", resp.Code)
+	}
+
+	var bindErr *BindError
+	err := (&Context{}).Bind(request{})
+	if err == nil || errors.As(err, &bindErr) || ErrorStatus(err) != http.StatusInternalServerError {
+		t.Errorf("绑定目标不是结构体指针时期望返回500错误，实际 %v", err)
+	}
+}
+
+func TestBindBody(t *testing.T) {
+	type user struct {
+		Name   string                `json:"name" xml:"name" form:"name"`
+		Age    any                   `json:"age" xml:"age" form:"age"`
+		Avatar *multipart.FileHeader `form:"avatar"`
+	}
+	RegisterDecoder("text/plain", func(ctx *Context, obj any) error {
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			return err
+		}
+		obj.(*user).Name = string(body)
+		return nil
+	})
+
+	var got user
+	app := New(Config{DisallowUnknownFields: true, UseNumber: true})
+	app.POST("/users", func(ctx *Context) error {
+		got = user{}
+		if err := ctx.BindBody(&got); err != nil {
+			return err
+		}
+		return ctx.NoContent()
+	})
+	send := func(contentType string, body io.Reader) int {
+		req := httptest.NewRequest(http.MethodPost, "/users", body)
+		req.Header.Set("Content-Type", contentType)
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	code := send("application/json; charset=utf-8", strings.NewReader(`{"name":"tom","age":18}`))
+	if code != http.StatusNoContent || got.Name != "tom" || got.Age != json.Number("18") {
+		t.Errorf("解码JSON错误：%d %+v", code, got)
+	}
+	if code := send("application/json", strings.NewReader(`{"name":"tom","email":"x"}`)); code != http.StatusBadRequest {
+		t.Errorf("未知字段期望 400，实际 %d", code)
+	}
+	code = send("application/xml", strings.NewReader(`<user><name>jerry</name></user>`))
+	if code != http.StatusNoContent || got.Name != "jerry" {
+		t.Errorf("解码XML错误：%d %+v", code, got)
+	}
+	if code := send("text/plain", strings.NewReader("spike")); code != http.StatusNoContent || got.Name != "spike" {
+		t.Errorf("自定义解码器错误：%d %+v", code, got)
+	}
+	if code := send("application/octet-stream", strings.NewReader("x")); code != http.StatusUnsupportedMediaType {
+		t.Errorf("期望 415，实际 %d", code)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("name", "tyke")
+	fw, _ := mw.CreateFormFile("avatar", "a.png")
+	_, _ = fw.Write([]byte("png"))
+	_ = mw.Close()
+	code = send(mw.FormDataContentType(), &body)
+	if code != http.StatusNoContent || got.Name != "tyke" || got.Avatar == nil || got.Avatar.Filename != "a.png" {
+		t.Errorf("解码multipart表单错误：%d %+v", code, got)
+	}
+}
+
+type rejectValidator struct{}
+
+func (rejectValidator) Validate(any) error {
+	return ValidationErrors{{Field: "Name", Rule: "custom"}}
+}
+
+func TestValidate(t *testing.T) {
+	type address struct {
+		City string `validate:"required"`
+	}
+	type user struct {
+		Name    string   `json:"name" validate:"required,min=2,max=10"`
+		Email   string   `json:"email" validate:"email"`
+		Role    string   `json:"role" validate:"oneof=admin user"`
+		Age     *int     `json:"age" validate:"min=1,max=150"`
+		Tags    []string `json:"tags" validate:"len=2"`
+		Address address  `json:"address"`
+	}
+
+	app := New(Config{
+		ErrorHandler: func(ctx *Context) {
+			var errs ValidationErrors
+			if errors.As(ctx.Error, &errs) {
+				_ = ctx.JSON(ErrorStatus(ctx.Error), errs)
+				return
+			}
+			ctx.ResponseWriter.WriteHeader(ctx.Status)
+		},
+	})
+	app.POST("/users", func(ctx *Context) error {
+		var u user
+		if err := ctx.BindBody(&u); err != nil {
+			return err
+		}
+		if err := ctx.Validate(&u); err != nil {
+			return err
+		}
+		return ctx.NoContent()
+	})
+	// 路径参数和请求体绑定到同一个结构体，全部绑定完成之后再验证
+	app.PUT("/users/:id", func(ctx *Context) error {
+		var req struct {
+			ID   int    `path:"id" validate:"required"`
+			Name string `json:"name" validate:"required"`
+		}
+		if err := ctx.BindAndValidate(&req); err != nil {
+			return err
+		}
+		return ctx.String(http.StatusOK, strconv.Itoa(req.ID)+":"+req.Name)
+	})
+	put := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, req)
+		return resp
+	}
+	if resp := put("/users/1", `{"name":"tom"}`); resp.Code != http.StatusOK || resp.Body.String() != "1:tom" {
+		t.Errorf("期望 200 1:tom，实际 %d %s", resp.Code, resp.Body.String())
+	}
+	if resp := put("/users/1", `{}`); resp.Code != http.StatusUnprocessableEntity ||
+		!strings.Contains(resp.Body.String(), `"field":"Name"`) {
+		t.Errorf("期望 422，实际 %d %s", resp.Code, resp.Body.String())
+	}
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := send(`{"name":"tom","email":"tom@example.com","role":"admin","tags":["a","b"],"address":{"city":"x"}}`)
+	if resp.Code != http.StatusNoContent {
+		t.Errorf("期望 204，实际 %d %s", resp.Code, resp.Body.String())
+	}
+
+	resp = send(`{"name":"t","email":"tom","role":"guest","age":0,"tags":["a"]}`)
+	var errs ValidationErrors
+	if resp.Code != http.StatusUnprocessableEntity || json.Unmarshal(resp.Body.Bytes(), &errs) != nil {
+		t.Fatalf("期望 422，实际 %d %s", resp.Code, resp.Body.String())
+	}
+	expected := ValidationErrors{
+		{Field: "Name", Rule: "min", Param: "2"},
+		{Field: "Email", Rule: "email"},
+		{Field: "Role", Rule: "oneof", Param: "admin user"},
+		{Field: "Age", Rule: "min", Param: "1"},
+		{Field: "Tags", Rule: "len", Param: "2"},
+		{Field: "Address.City", Rule: "required"},
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("期望 %v，实际 %v", expected, errs)
+	}
+	for i := range expected {
+		if errs[i] != expected[i] {
+			t.Errorf("期望 %v，实际 %v", expected[i], errs[i])
+		}
+	}
+
+	// 自定义验证器
+	app.config.Validator = rejectValidator{}
+	resp = send(`{"name":"tom"}`)
+	if resp.Code != http.StatusUnprocessableEntity || !strings.Contains(resp.Body.String(), "custom") {
+		t.Errorf("期望自定义验证器返回 422，实际 %d %s", resp.Code, resp.Body.String())
+	}
+}
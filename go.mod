@@ -1,3 +1,3 @@
 module github.com/dxvgef/tsing/v2
 
-go 1.18
+go 1.21
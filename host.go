@@ -0,0 +1,122 @@
+package tsing
+
+import (
+	"fmt"
+	"strings"
+)
+
+// hostRouter 主机名路由，包含匹配该主机名的所有方法的路由树
+type hostRouter struct {
+	pattern string
+	labels  []string // 主机名按'.'分割后的各个部份，以':'开头的部份是参数
+	params  int      // 参数的数量
+	trees   methodTrees
+}
+
+// normalizeHost 将主机名转为小写并去除结尾的'.'
+func normalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+func newHostRouter(pattern string) (*hostRouter, error) {
+	pattern = normalizeHost(pattern)
+	if pattern == "" {
+		return nil, fmt.Errorf("%w: host can not be empty", ErrInvalidRoute)
+	}
+
+	host := &hostRouter{
+		pattern: pattern,
+		labels:  strings.Split(pattern, "."),
+		trees:   make(methodTrees, 0, 9),
+	}
+	for _, label := range host.labels {
+		if label == "" {
+			return nil, fmt.Errorf("%w: empty label in host '%s'", ErrInvalidRoute, pattern)
+		}
+		if label[0] != ':' {
+			if strings.ContainsAny(label, ":*/<>?") {
+				return nil, fmt.Errorf("%w: invalid label '%s' in host '%s'", ErrInvalidRoute, label, pattern)
+			}
+			continue
+		}
+		if len(label) == 1 {
+			return nil, fmt.Errorf("%w: wildcards must be named with a non-empty name in host '%s'",
+				ErrInvalidRoute, pattern)
+		}
+		for i := 1; i < len(label); i++ {
+			if !isNameChar(label[i]) {
+				return nil, fmt.Errorf("%w: invalid wildcard '%s' in host '%s'", ErrInvalidRoute, label, pattern)
+			}
+		}
+		host.params++
+	}
+	return host, nil
+}
+
+// match 判断主机名是否匹配，参数值会追加到 params，不匹配时 params 恢复原状
+func (host *hostRouter) match(name string, params *Params) bool {
+	count := len(*params)
+	for i, label := range host.labels {
+		end := strings.IndexByte(name, '.')
+		if (end < 0) != (i == len(host.labels)-1) {
+			*params = (*params)[:count]
+			return false
+		}
+		part := name
+		if end >= 0 {
+			part, name = name[:end], name[end+1:]
+		}
+
+		if label[0] == ':' {
+			if part == "" {
+				*params = (*params)[:count]
+				return false
+			}
+			*params = append(*params, Param{Key: label[1:], Value: part})
+		} else if !strings.EqualFold(part, label) {
+			*params = (*params)[:count]
+			return false
+		}
+	}
+	return true
+}
+
+// host 获取主机名路由，不存在则创建，不含参数的主机名优先匹配
+func (table *routeTable) host(pattern string) (*hostRouter, error) {
+	normalized := normalizeHost(pattern)
+	for _, host := range table.hosts {
+		if host.pattern == normalized {
+			return host, nil
+		}
+	}
+
+	host, err := newHostRouter(pattern)
+	if err != nil {
+		return nil, err
+	}
+	i := len(table.hosts)
+	for i > 0 && table.hosts[i-1].params > host.params {
+		i--
+	}
+	table.hosts = append(table.hosts, nil)
+	copy(table.hosts[i+1:], table.hosts[i:])
+	table.hosts[i] = host
+	return host, nil
+}
+
+// matchHost 查找与请求的主机名匹配的主机名路由，主机名中的端口会被忽略
+func (table *routeTable) matchHost(name string, params *Params) *hostRouter {
+	if len(table.hosts) == 0 {
+		return nil
+	}
+	if i := strings.LastIndexByte(name, ':'); i >= 0 && strings.IndexByte(name[i:], ']') < 0 {
+		name = name[:i]
+	}
+	name = strings.TrimSuffix(name, ".")
+	for _, host := range table.hosts {
+		if host.match(name, params) {
+			return host
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clfTimeFormat Common Log Format 的时间格式
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+var quoteReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// Common 使用 Common Log Format 格式化日志，例如：
+// 127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /index.html HTTP/1.1" 200 2326
+func Common(entry *Entry) string {
+	var buf strings.Builder
+	writeCommon(&buf, entry)
+	return buf.String()
+}
+
+// Combined 使用 Combined Log Format 格式化日志，在 Common Log Format 之后加上 Referer 和 User-Agent，例如：
+// 127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /index.html HTTP/1.1" 200 2326 "http://example.com/" "curl/8.0"
+func Combined(entry *Entry) string {
+	var buf strings.Builder
+	writeCommon(&buf, entry)
+	buf.WriteString(` "`)
+	buf.WriteString(quoteReplacer.Replace(entry.Referer))
+	buf.WriteString(`" "`)
+	buf.WriteString(quoteReplacer.Replace(entry.UserAgent))
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+func writeCommon(buf *strings.Builder, entry *Entry) {
+	buf.WriteString(dash(entry.RemoteAddr))
+	buf.WriteString(" - - [")
+	buf.WriteString(entry.Time.Format(clfTimeFormat))
+	buf.WriteString(`] "`)
+	buf.WriteString(quoteReplacer.Replace(entry.Method + " " + entry.URI + " " + entry.Proto))
+	buf.WriteString(`" `)
+	buf.WriteString(strconv.Itoa(entry.Status))
+	buf.WriteByte(' ')
+	if entry.Bytes > 0 {
+		buf.WriteString(strconv.Itoa(entry.Bytes))
+	} else {
+		buf.WriteByte('-')
+	}
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+type jsonEntry struct {
+	Time       string  `json:"time"`
+	Method     string  `json:"method"`
+	URI        string  `json:"uri"`
+	FullPath   string  `json:"full_path"`
+	Status     int     `json:"status"`
+	Latency    float64 `json:"latency_ms"`
+	Bytes      int     `json:"bytes"`
+	RemoteAddr string  `json:"remote_addr"`
+	RequestID  string  `json:"request_id,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// JSON 将日志格式化为一行JSON，耗时的单位为毫秒，例如：
+// {"time":"2000-10-10T13:55:36-07:00","method":"GET","uri":"/users/1","full_path":"/users/:id","status":200,...}
+func JSON(entry *Entry) string {
+	data := jsonEntry{
+		Time:       entry.Time.Format(time.RFC3339),
+		Method:     entry.Method,
+		URI:        entry.URI,
+		FullPath:   entry.FullPath,
+		Status:     entry.Status,
+		Latency:    float64(entry.Latency) / float64(time.Millisecond),
+		Bytes:      entry.Bytes,
+		RemoteAddr: entry.RemoteAddr,
+		RequestID:  entry.RequestID,
+	}
+	if entry.Error != nil {
+		data.Error = entry.Error.Error()
+	}
+	buf, _ := json.Marshal(data) //nolint:errcheck,errchkjson
+	return string(buf)
+}
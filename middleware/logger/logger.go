@@ -0,0 +1,147 @@
+// Package logger 提供访问日志中间件，支持 Common/Combined Log Format、JSON 格式以及 log/slog 结构化日志
+package logger
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dxvgef/tsing/v2"
+)
+
+// Entry 一条访问日志
+type Entry struct {
+	Time       time.Time     // 请求开始的时间
+	Method     string        // 请求方法
+	URI        string        // 请求的原始URI，包含查询参数
+	Path       string        // 请求路径
+	FullPath   string        // 路由注册时的路径，路由未命中时为空
+	Proto      string        // 协议版本，例如 HTTP/1.1
+	Status     int           // 响应状态码
+	Latency    time.Duration // 处理耗时
+	Bytes      int           // 响应体字节数
+	RemoteAddr string        // 客户端IP
+	RequestID  string        // 请求ID，由 middleware/requestid 设置
+	Referer    string        // 请求头 Referer
+	UserAgent  string        // 请求头 User-Agent
+	Error      error         // 处理器返回的错误
+}
+
+// Attrs 返回日志条目的结构化字段
+func (entry *Entry) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("method", entry.Method),
+		slog.String("uri", entry.URI),
+		slog.String("full_path", entry.FullPath),
+		slog.Int("status", entry.Status),
+		slog.Duration("latency", entry.Latency),
+		slog.Int("bytes", entry.Bytes),
+		slog.String("remote_addr", entry.RemoteAddr),
+	}
+	if entry.RequestID != "" {
+		attrs = append(attrs, slog.String("request_id", entry.RequestID))
+	}
+	if entry.Error != nil {
+		attrs = append(attrs, slog.String("error", entry.Error.Error()))
+	}
+	return attrs
+}
+
+// Formatter 将日志条目格式化为一行文本，不包含结尾的换行符
+type Formatter func(entry *Entry) string
+
+// Config 日志中间件配置
+type Config struct {
+	Output    io.Writer    // Formatter 格式化后的日志输出目标，默认为 os.Stdout
+	Formatter Formatter    // 日志格式，默认为 Common
+	Logger    *slog.Logger // 设置后使用 slog 记录结构化日志，忽略 Output 和 Formatter
+	SkipPaths []string     // 不记录日志的请求路径
+}
+
+// New 新建访问日志中间件，应在其它中间件之前注册，以便记录完整的处理耗时和结果。
+// 通过 app.Use() 注册时只记录命中路由的请求，通过 app.Pre() 注册时还会记录404、405、自动响应的OPTIONS、
+// 重定向以及panic的请求
+func New(config ...Config) tsing.Handler {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Output == nil {
+		cfg.Output = os.Stdout
+	}
+	if cfg.Formatter == nil {
+		cfg.Formatter = Common
+	}
+	skipPaths := make(map[string]struct{}, len(cfg.SkipPaths))
+	for _, p := range cfg.SkipPaths {
+		skipPaths[p] = struct{}{}
+	}
+
+	var mu sync.Mutex
+	return func(ctx *tsing.Context) error {
+		if _, skip := skipPaths[ctx.Request.URL.Path]; skip {
+			return ctx.Next()
+		}
+
+		start := time.Now()
+		err := ctx.Next()
+		entry := newEntry(ctx, start, err)
+
+		if cfg.Logger != nil {
+			cfg.Logger.LogAttrs(ctx.Request.Context(), level(entry.Status), "request", entry.Attrs()...)
+			return err
+		}
+		line := cfg.Formatter(&entry) + "\n"
+		mu.Lock()
+		_, _ = io.WriteString(cfg.Output, line) //nolint:errcheck
+		mu.Unlock()
+		return err
+	}
+}
+
+func newEntry(ctx *tsing.Context, start time.Time, err error) Entry {
+	req := ctx.Request
+	status := ctx.Writer().Status()
+	// 处理器返回的错误在中间件之后才写入响应
+	if err != nil && !ctx.Writer().Written() {
+		status = tsing.ErrorStatus(err)
+	}
+	// 通过 Pre() 注册时，路由未命中、处理器的错误和panic已经由引擎处理，只记录在 ctx.Error 中
+	if err == nil && ctx.Error != nil {
+		err = ctx.Error
+		if !ctx.Writer().Written() {
+			status = ctx.Status
+		}
+	}
+	return Entry{
+		Time:       start,
+		Method:     req.Method,
+		URI:        req.RequestURI,
+		Path:       req.URL.Path,
+		FullPath:   ctx.FullPath(),
+		Proto:      req.Proto,
+		Status:     status,
+		Latency:    time.Since(start),
+		Bytes:      ctx.Writer().Size(),
+		RemoteAddr: ctx.GetRemoteAddr(),
+		RequestID:  ctx.RequestID(),
+		Referer:    req.Referer(),
+		UserAgent:  req.UserAgent(),
+		Error:      err,
+	}
+}
+
+// level 根据状态码确定日志级别
+func level(status int) slog.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return slog.LevelError
+	case status >= http.StatusBadRequest:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -0,0 +1,116 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dxvgef/tsing/v2"
+)
+
+func newApp(config Config) *tsing.Engine {
+	app := tsing.New()
+	app.Use(New(config))
+	app.GET("/users/:id", func(ctx *tsing.Context) error {
+		return ctx.String(http.StatusOK, "hello")
+	})
+	app.GET("/error", func(ctx *tsing.Context) error {
+		return errors.New("failed")
+	})
+	app.GET("/health", func(ctx *tsing.Context) error {
+		return ctx.NoContent()
+	})
+	return app
+}
+
+func serve(app *tsing.Engine, path string) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("User-Agent", "test")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	app := newApp(Config{Output: &buf, Formatter: Combined, SkipPaths: []string{"/health"}})
+	serve(app, "/users/1?a=1")
+	serve(app, "/health")
+	serve(app, "/error")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("期望 2 行日志，实际 %q", buf.String())
+	}
+	pattern := regexp.MustCompile(`^10\.0\.0\.1 - - \[[^]]+\] "GET /users/1\?a=1 HTTP/1\.1" 200 5 "" "test"$`)
+	if !pattern.MatchString(lines[0]) {
+		t.Errorf("Combined 格式错误：%s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"GET /error HTTP/1.1" 500 -`) {
+		t.Errorf("错误请求的日志格式错误：%s", lines[1])
+	}
+
+	buf.Reset()
+	serve(newApp(Config{Output: &buf, Formatter: JSON}), "/users/2")
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["full_path"] != "/users/:id" || entry["status"] != float64(200) || entry["bytes"] != float64(5) {
+		t.Errorf("JSON 格式错误：%s", buf.String())
+	}
+
+	buf.Reset()
+	serve(newApp(Config{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}), "/error")
+	entry = nil
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["level"] != "ERROR" || entry["status"] != float64(500) || entry["error"] != "failed" {
+		t.Errorf("slog 日志错误：%s", buf.String())
+	}
+}
+
+// 通过 Pre() 注册时记录未命中路由和panic的请求
+func TestLoggerPre(t *testing.T) {
+	var buf bytes.Buffer
+	app := tsing.New(tsing.Config{Recovery: true, HandleMethodNotAllowed: true})
+	app.Pre(New(Config{Output: &buf, Formatter: JSON}))
+	app.GET("/panic", func(ctx *tsing.Context) error {
+		panic("boom")
+	})
+	app.GET("/error", func(ctx *tsing.Context) error {
+		return errors.New("failed")
+	})
+	app.POST("/users", func(ctx *tsing.Context) error {
+		return ctx.NoContent()
+	})
+
+	cases := []struct {
+		method, path string
+		status       int
+		err          string
+	}{
+		{http.MethodGet, "/none", http.StatusNotFound, "Not Found"},
+		{http.MethodGet, "/users", http.StatusMethodNotAllowed, "Method Not Allowed"},
+		{http.MethodGet, "/panic", http.StatusInternalServerError, "boom"},
+		{http.MethodGet, "/error", http.StatusInternalServerError, "failed"},
+		{http.MethodPost, "/users", http.StatusNoContent, ""},
+	}
+	for _, c := range cases {
+		buf.Reset()
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(c.method, c.path, nil))
+		var entry map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("%s %s: %v %q", c.method, c.path, err, buf.String())
+		}
+		if entry["status"] != float64(c.status) || c.err != "" && entry["error"] != c.err {
+			t.Errorf("%s %s: 期望 %d %q，实际 %s", c.method, c.path, c.status, c.err, buf.String())
+		}
+	}
+}
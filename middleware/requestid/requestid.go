@@ -0,0 +1,67 @@
+// Package requestid 提供请求ID中间件，从请求头读取或生成请求ID，保存到 Context 并写入响应头
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/dxvgef/tsing/v2"
+)
+
+// DefaultHeader 默认的请求ID头
+const DefaultHeader = "X-Request-ID"
+
+// maxLength 从请求头读取的请求ID的最大长度，超出或包含非可见字符时重新生成
+const maxLength = 128
+
+// Config 请求ID中间件配置
+type Config struct {
+	Header    string        // 读取和写入请求ID的头，默认为 X-Request-ID
+	Generator func() string // 请求头中没有有效的请求ID时用于生成请求ID，默认生成32位十六进制随机字符串
+}
+
+// New 新建请求ID中间件，请求ID通过 ctx.RequestID() 获取。
+// 通过 app.Use() 注册时只有命中路由的请求才有请求ID，需要在404、405等请求的 ErrorHandler 中
+// 获取请求ID时应通过 app.Pre() 注册
+func New(config ...Config) tsing.Handler {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Header == "" {
+		cfg.Header = DefaultHeader
+	}
+	if cfg.Generator == nil {
+		cfg.Generator = Generate
+	}
+
+	return func(ctx *tsing.Context) error {
+		id := ctx.Request.Header.Get(cfg.Header)
+		if !valid(id) {
+			id = cfg.Generator()
+		}
+		ctx.SetRequestID(id)
+		ctx.ResponseWriter.Header().Set(cfg.Header, id)
+		return nil
+	}
+}
+
+// Generate 生成32位十六进制随机字符串
+func Generate() string {
+	var buf [16]byte
+	_, _ = rand.Read(buf[:]) //nolint:errcheck
+	return hex.EncodeToString(buf[:])
+}
+
+// valid 判断客户端传入的请求ID是否有效，防止日志注入
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dxvgef/tsing/v2"
+)
+
+func TestRequestID(t *testing.T) {
+	var afterID string
+	app := tsing.New(tsing.Config{
+		AfterHandler: func(ctx *tsing.Context) {
+			afterID = ctx.RequestID()
+		},
+	})
+	app.Use(New())
+	app.GET("/id", func(ctx *tsing.Context) error {
+		return ctx.String(http.StatusOK, ctx.RequestID())
+	})
+
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/id", nil))
+	id := resp.Header().Get(DefaultHeader)
+	if len(id) != 32 || resp.Body.String() != id || afterID != id {
+		t.Errorf("生成的请求ID错误：%q %q %q", id, resp.Body.String(), afterID)
+	}
+
+	for input, keep := range map[string]bool{"abc-123": true, "a b": false, strings.Repeat("a", 200): false} {
+		req := httptest.NewRequest(http.MethodGet, "/id", nil)
+		req.Header.Set(DefaultHeader, input)
+		resp = httptest.NewRecorder()
+		app.ServeHTTP(resp, req)
+		if got := resp.Header().Get(DefaultHeader); (got == input) != keep {
+			t.Errorf("%q: 期望保留 %v，实际 %q", input, keep, got)
+		}
+	}
+
+	app = tsing.New()
+	app.Use(New(Config{Header: "X-Trace", Generator: func() string { return "fixed" }}))
+	app.GET("/id", func(ctx *tsing.Context) error {
+		return ctx.NoContent()
+	})
+	resp = httptest.NewRecorder()
+	app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/id", nil))
+	if resp.Header().Get("X-Trace") != "fixed" {
+		t.Errorf("期望 fixed，实际 %q", resp.Header().Get("X-Trace"))
+	}
+}
+
+// 通过 Pre() 注册时，未命中路由的请求也有请求ID
+func TestRequestIDPre(t *testing.T) {
+	var errorID string
+	app := tsing.New(tsing.Config{
+		ErrorHandler: func(ctx *tsing.Context) {
+			errorID = ctx.RequestID()
+			ctx.ResponseWriter.WriteHeader(ctx.Status)
+		},
+	})
+	app.Pre(New())
+	app.GET("/id", func(ctx *tsing.Context) error {
+		return ctx.NoContent()
+	})
+
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/none", nil))
+	id := resp.Header().Get(DefaultHeader)
+	if resp.Code != http.StatusNotFound || len(id) != 32 || errorID != id {
+		t.Errorf("期望 404 和请求ID，实际 %d %q %q", resp.Code, id, errorID)
+	}
+}
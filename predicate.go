@@ -0,0 +1,86 @@
+package tsing
+
+import (
+	"strings"
+)
+
+// Predicate 路由匹配条件，在路由树中找到路径之后判断，返回 true 表示满足条件
+type Predicate func(ctx *Context) bool
+
+// HeaderIs 请求头 key 的值等于 value
+func HeaderIs(key, value string) Predicate {
+	return func(ctx *Context) bool {
+		return ctx.Request.Header.Get(key) == value
+	}
+}
+
+// HeaderExists 存在请求头 key
+func HeaderExists(key string) Predicate {
+	return func(ctx *Context) bool {
+		return len(ctx.Request.Header.Values(key)) > 0
+	}
+}
+
+// QueryIs 查询参数 key 的值等于 value
+func QueryIs(key, value string) Predicate {
+	return func(ctx *Context) bool {
+		return ctx.QueryValue(key) == value
+	}
+}
+
+// QueryExists 存在查询参数 key
+func QueryExists(key string) Predicate {
+	return func(ctx *Context) bool {
+		_, exist := ctx.QueryParam(key)
+		return exist
+	}
+}
+
+// Accepts 请求头 Accept 中包含媒体类型 mediaType，例如 "application/json"，
+// Accept 中的 "type/*" 也视为包含，"*/*" 则不算，以免客户端未指定类型时总是命中
+func Accepts(mediaType string) Predicate {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+	return func(ctx *Context) bool {
+		for _, accept := range ctx.Request.Header.Values("Accept") {
+			for _, item := range strings.Split(accept, ",") {
+				item, _, _ = strings.Cut(item, ";")
+				item = strings.TrimSpace(item)
+				if strings.EqualFold(item, mediaType) ||
+					(strings.HasSuffix(item, "/*") && strings.EqualFold(item[:len(item)-2], mainType)) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// ContentTypeIs 请求头 Content-Type 的媒体类型等于 mediaType，忽略参数，例如 "application/json"
+func ContentTypeIs(mediaType string) Predicate {
+	return func(ctx *Context) bool {
+		contentType, _, _ := strings.Cut(ctx.Request.Header.Get("Content-Type"), ";")
+		return strings.EqualFold(strings.TrimSpace(contentType), mediaType)
+	}
+}
+
+// match 判断请求是否满足路由的所有匹配条件
+func (route *Route) match(ctx *Context) bool {
+	for _, predicate := range route.predicates {
+		if !predicate(ctx) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchRoute 按注册顺序匹配带有条件的路由，都不满足时使用不带条件的路由，返回是否命中
+func (value *nodeValue) matchRoute(ctx *Context) bool {
+	for _, route := range value.routes {
+		if route.match(ctx) {
+			value.handlers = route.handlers
+			value.route = route
+			return true
+		}
+	}
+	return value.route != nil
+}
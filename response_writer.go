@@ -0,0 +1,145 @@
+package tsing
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ResponseWriter 记录了响应状态的 http.ResponseWriter，通过 Context.Writer() 获取
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+	io.ReaderFrom
+
+	// Status 返回已写入的状态码，未写入时返回200
+	Status() int
+	// Size 返回已写入的响应体字节数
+	Size() int
+	// Written 判断是否已经写入了响应头
+	Written() bool
+	// Unwrap 返回原始的 http.ResponseWriter，用于 http.ResponseController
+	Unwrap() http.ResponseWriter
+}
+
+// responseWriter 随 Context 一起复用，不需要为每个请求分配内存
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	size    int
+	written bool
+}
+
+var _ ResponseWriter = (*responseWriter)(nil)
+
+func (w *responseWriter) reset(writer http.ResponseWriter) {
+	w.ResponseWriter = writer
+	w.status = http.StatusOK
+	w.size = 0
+	w.written = false
+}
+
+// WriteHeader 写入状态码，已经写入过响应头时忽略，以免重复写入
+func (w *responseWriter) WriteHeader(code int) {
+	if w.written {
+		return
+	}
+	// 1xx 状态码（101除外）可以在最终的状态码之前写入多次
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+	w.written = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write 写入响应体，未写入响应头时先写入200状态码
+func (w *responseWriter) Write(data []byte) (int, error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+// WriteString 写入字符串响应体
+func (w *responseWriter) WriteString(s string) (int, error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := io.WriteString(w.ResponseWriter, s)
+	w.size += n
+	return n, err
+}
+
+// ReadFrom 从 r 读取数据写入响应体，原始的 http.ResponseWriter 实现了 io.ReaderFrom 时使用它（例如 sendfile）
+func (w *responseWriter) ReadFrom(r io.Reader) (n int64, err error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(writerOnly{w.ResponseWriter}, r)
+	}
+	w.size += int(n)
+	return n, err
+}
+
+// Flush 将缓冲的数据发送到客户端
+func (w *responseWriter) Flush() {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack 接管连接，原始的 http.ResponseWriter 不支持时返回错误
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the ResponseWriter doesn't support the Hijacker interface")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		w.written = true
+	}
+	return conn, rw, err
+}
+
+// Push 发起 HTTP/2 服务器推送，原始的 http.ResponseWriter 不支持时返回 http.ErrNotSupported
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.written
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// writerOnly 隐藏 io.ReaderFrom 接口，防止 io.Copy 递归调用 ReadFrom
+type writerOnly struct {
+	io.Writer
+}
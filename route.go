@@ -0,0 +1,160 @@
+package tsing
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Route 路由，由 GET()、Handle() 等路由注册方法返回
+type Route struct {
+	Host   string         // 主机名，为空时表示默认路由
+	Method string         // 请求方法
+	Path   string         // 路由注册时的完整路径
+	Meta   map[string]any // 路由元数据，例如权限范围、限流类别、接口文档摘要等，处理器中通过 ctx.Route().Meta 读取
+
+	name       string
+	engine     *Engine
+	table      *routeTable
+	building   bool // 是否属于 Reload() 中正在构建的路由表
+	handlers   HandlersChain
+	predicates []Predicate // 匹配条件，为空时是路径的默认路由
+}
+
+// Name 设置路由名称，名称在引擎中必须唯一，用于 Engine.URL() 反向生成URL。
+// 开始处理请求之后设置名称时，会复制当前的路由表并原子替换，不影响并发的 Engine.URL() 调用
+func (route *Route) Name(name string) *Route {
+	if name == "" {
+		panic("route name cannot be empty")
+	}
+	// Reload() 中构建的路由表在替换之前只有 fn 可以访问，直接修改
+	if route.building {
+		route.table.setName(route, name)
+		return route
+	}
+
+	engine := route.engine
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	table := route.table
+	if table != engine.table.Load() {
+		// 路由已被删除或其路由表已被替换，名称不会再被使用
+		route.name = name
+		return route
+	}
+	if !engine.serving.Load() {
+		table.setName(route, name)
+		return route
+	}
+	c := *table
+	c.names = make(map[string]*Route, len(table.names)+1)
+	for k, v := range table.names {
+		c.names[k] = v
+	}
+	c.setName(route, name)
+	engine.table.Store(&c)
+	c.adoptRoutes()
+	return route
+}
+
+// Remove 从引擎中删除该路由，与 RemoveRoute 不同的是可以删除通过 With() 注册的带有匹配条件的路由，
+// 路径中的其它路由不受影响。路由已被删除或其路由表已被 Reload() 替换时返回 ErrRouteNotFound，
+// 不能在 Reload() 的 fn 中调用
+func (route *Route) Remove() error {
+	engine := route.engine
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	table := engine.table.Load().clone()
+	if !table.removeRoute(route.Host, route.Method, route.Path, route) {
+		return fmt.Errorf("%w: %s %s", ErrRouteNotFound, route.Method, route.Path)
+	}
+	engine.table.Store(table)
+	table.adoptRoutes()
+	return nil
+}
+
+// SetMeta 设置路由元数据，只能在开始处理请求之前或 Reload() 的 fn 中设置，否则触发panic，
+// 开始处理请求之后注册的路由可以通过 RouterGroup.SetMeta() 在注册时设置元数据
+func (route *Route) SetMeta(key string, value any) *Route {
+	if !route.building && route.engine.serving.Load() {
+		panic("route meta cannot be modified after the engine starts serving requests")
+	}
+	if route.Meta == nil {
+		route.Meta = make(map[string]any)
+	}
+	route.Meta[key] = value
+	return route
+}
+
+// GetName 获取路由名称
+func (route *Route) GetName() string {
+	return route.name
+}
+
+// URL 使用参数值依次替换路由路径中的 :param 和 *catchall 参数，生成URL路径
+// 参数值会进行URL转义，*catchall 参数值中的'/'会被保留，参数值不满足 :param 的约束时返回错误
+// 可选参数可以不提供参数值，此时生成的路径中不包含该参数
+func (route *Route) URL(params ...any) (string, error) {
+	var buf strings.Builder
+	path := route.Path
+	count := 0
+
+	for {
+		wildcard, i, _ := findWildcard(path)
+		if i < 0 {
+			buf.WriteString(path)
+			break
+		}
+		end := i + len(wildcard)
+		optional := false
+		if wildcard[0] == '*' && strings.HasSuffix(wildcard, "?") {
+			optional = true
+			wildcard = wildcard[:len(wildcard)-1]
+		} else if end < len(path) && path[end] == '?' {
+			optional = true
+			end++
+		}
+		if count >= len(params) {
+			if optional {
+				// 省略未提供值的可选参数及其之前的'/'，可选参数只会出现在路径的结尾
+				buf.WriteString(path[:i-1])
+				break
+			}
+			return "", fmt.Errorf("missing value for '%s' in route '%s'", wildcard, route.Path)
+		}
+		value := fmt.Sprint(params[count])
+		count++
+
+		buf.WriteString(path[:i])
+		if wildcard[0] == ':' {
+			if value == "" {
+				return "", fmt.Errorf("value for '%s' in route '%s' cannot be empty", wildcard, route.Path)
+			}
+			if _, expr := splitWildcard(wildcard); expr != "" {
+				if match, err := compileConstraint(expr); err == nil && !match(value) {
+					return "", fmt.Errorf("value '%s' for '%s' in route '%s' doesn't satisfy the constraint",
+						value, wildcard, route.Path)
+				}
+			}
+			buf.WriteString(url.PathEscape(value))
+		} else {
+			// catch-all 参数前已经有'/'
+			segments := strings.Split(strings.TrimPrefix(value, "/"), "/")
+			for k := range segments {
+				segments[k] = url.PathEscape(segments[k])
+			}
+			buf.WriteString(strings.Join(segments, "/"))
+		}
+		path = path[end:]
+	}
+
+	if count < len(params) {
+		return "", fmt.Errorf("too many values for route '%s', expected %d, got %d", route.Path, count, len(params))
+	}
+	if buf.Len() == 0 {
+		return "/", nil
+	}
+	return buf.String(), nil
+}
@@ -13,28 +13,35 @@ import (
 type Router interface {
 	Routes
 	Group(path string, handlers ...Handler) *RouterGroup
+	Host(pattern string) *RouterGroup
+	With(predicates ...Predicate) *RouterGroup
 }
 
 // Routes 定义所有路由器接口
 type Routes interface {
 	Use(handlers ...Handler)
-	Handle(method string, path string, handlers ...Handler)
-	GET(path string, handlers ...Handler)
-	POST(path string, handlers ...Handler)
-	DELETE(path string, handlers ...Handler)
-	PATCH(path string, handlers ...Handler)
-	PUT(path string, handlers ...Handler)
-	OPTIONS(path string, handlers ...Handler)
-	HEAD(path string, handlers ...Handler)
+	Handle(method string, path string, handlers ...Handler) *Route
+	TryHandle(method string, path string, handlers ...Handler) (*Route, error)
+	GET(path string, handlers ...Handler) *Route
+	POST(path string, handlers ...Handler) *Route
+	DELETE(path string, handlers ...Handler) *Route
+	PATCH(path string, handlers ...Handler) *Route
+	PUT(path string, handlers ...Handler) *Route
+	OPTIONS(path string, handlers ...Handler) *Route
+	HEAD(path string, handlers ...Handler) *Route
 	Match(methods []string, path string, handlers ...Handler)
 }
 
 // RouterGroup 路由组
 type RouterGroup struct {
-	handlers HandlersChain
-	basePath string
-	engine   *Engine
-	root     bool
+	handlers   HandlersChain
+	basePath   string
+	engine     *Engine
+	table      *routeTable // 路由注册的目标路由表，为nil时使用引擎当前的路由表
+	meta       map[string]any
+	host       string // 主机名，为空时注册到默认的路由树
+	predicates []Predicate
+	root       bool
 }
 
 // Use 使用中间件
@@ -42,59 +49,140 @@ func (group *RouterGroup) Use(handlers ...Handler) {
 	group.handlers = append(group.handlers, handlers...)
 }
 
+// SetMeta 设置路由组的元数据，之后在该路由组中注册的路由和子路由组都会继承
+func (group *RouterGroup) SetMeta(key string, value any) *RouterGroup {
+	if group.meta == nil {
+		group.meta = make(map[string]any)
+	}
+	group.meta[key] = value
+	return group
+}
+
 // Group 注册路由组
 func (group *RouterGroup) Group(relativePath string, handlers ...Handler) *RouterGroup {
 	return &RouterGroup{
-		handlers: group.combineHandlers(handlers),
-		basePath: group.calculateAbsolutePath(relativePath),
-		engine:   group.engine,
+		handlers:   group.combineHandlers(handlers),
+		basePath:   group.calculateAbsolutePath(relativePath),
+		engine:     group.engine,
+		table:      group.table,
+		meta:       group.copyMeta(),
+		host:       group.host,
+		predicates: group.predicates,
+	}
+}
+
+// Host 返回指定主机名的路由组，在该路由组中注册的路由只匹配主机名相同的请求，例如：
+// app.Host("api.example.com").GET("/users", handler)
+// 主机名中以':'开头的部份是参数，例如 ":tenant.example.com"，处理器中通过 ctx.PathValue("tenant") 获取参数值。
+// 主机名不区分大小写并忽略端口，请求的主机名未匹配或在主机名的路由中未找到路由时，会回退到默认的路由
+func (group *RouterGroup) Host(pattern string) *RouterGroup {
+	return &RouterGroup{
+		handlers:   group.combineHandlers(nil),
+		basePath:   group.basePath,
+		engine:     group.engine,
+		table:      group.table,
+		meta:       group.copyMeta(),
+		host:       pattern,
+		predicates: group.predicates,
+	}
+}
+
+// With 返回带有匹配条件的路由组，在该路由组中注册的路由只有满足所有条件时才会命中，例如：
+// app.With(HeaderIs("X-API-Version", "2")).GET("/users", listUsersV2)
+// 同一路径可以注册多个条件不同的路由，按注册顺序匹配，都不满足时使用该路径不带条件的路由，
+// 条件只在路由树中找到路径之后判断，不影响没有条件的路由的性能
+func (group *RouterGroup) With(predicates ...Predicate) *RouterGroup {
+	return &RouterGroup{
+		handlers:   group.combineHandlers(nil),
+		basePath:   group.basePath,
+		engine:     group.engine,
+		table:      group.table,
+		meta:       group.copyMeta(),
+		host:       group.host,
+		predicates: append(group.predicates[:len(group.predicates):len(group.predicates)], predicates...),
+	}
+}
+
+func (group *RouterGroup) handle(httpMethod, relativePath string, handlers HandlersChain) *Route {
+	route, err := group.tryHandle(httpMethod, relativePath, handlers)
+	if err != nil {
+		panic(err)
 	}
+	return route
 }
 
-func (group *RouterGroup) handle(httpMethod, relativePath string, handlers HandlersChain) {
+func (group *RouterGroup) tryHandle(httpMethod, relativePath string, handlers HandlersChain) (*Route, error) {
 	absolutePath := group.calculateAbsolutePath(relativePath)
 	handlers = group.combineHandlers(handlers)
-	group.engine.addRoute(httpMethod, absolutePath, handlers)
+	route := &Route{
+		Host:       group.host,
+		Method:     httpMethod,
+		Path:       absolutePath,
+		Meta:       group.copyMeta(),
+		engine:     group.engine,
+		table:      group.table,
+		building:   group.table != nil,
+		handlers:   handlers,
+		predicates: group.predicates,
+	}
+	// Reload() 中构建的路由表在替换之前只有 fn 可以访问，直接注册
+	if group.table != nil {
+		if err := group.table.addRoute(group.host, httpMethod, absolutePath, handlers, route); err != nil {
+			return nil, err
+		}
+		return route, nil
+	}
+	if err := group.engine.addRoute(route); err != nil {
+		return nil, err
+	}
+	return route, nil
+}
+
+// Handle 注册自定义方法的路由，路由无效或与已注册的路由冲突时会触发panic
+func (group *RouterGroup) Handle(httpMethod, relativePath string, handlers ...Handler) *Route {
+	return group.handle(httpMethod, relativePath, handlers)
 }
 
-// Handle 注册自定义方法的路由
-func (group *RouterGroup) Handle(httpMethod, relativePath string, handlers ...Handler) {
-	group.handle(httpMethod, relativePath, handlers)
+// TryHandle 注册自定义方法的路由，与 Handle() 不同的是路由无效或冲突时返回错误而不是触发panic，
+// 适用于在运行时从插件或配置中加载路由。可能返回的错误：
+// ErrInvalidRoute、ErrDuplicateRoute（使用errors.Is判断）以及 *ErrRouteConflict（使用errors.As判断）
+func (group *RouterGroup) TryHandle(httpMethod, relativePath string, handlers ...Handler) (*Route, error) {
+	return group.tryHandle(httpMethod, relativePath, handlers)
 }
 
 // POST 注册POST方法的路由
-func (group *RouterGroup) POST(relativePath string, handlers ...Handler) {
-	group.handle(http.MethodPost, relativePath, handlers)
+func (group *RouterGroup) POST(relativePath string, handlers ...Handler) *Route {
+	return group.handle(http.MethodPost, relativePath, handlers)
 }
 
 // GET 注册GET方法的路由
-func (group *RouterGroup) GET(relativePath string, handlers ...Handler) {
-	group.handle(http.MethodGet, relativePath, handlers)
+func (group *RouterGroup) GET(relativePath string, handlers ...Handler) *Route {
+	return group.handle(http.MethodGet, relativePath, handlers)
 }
 
 // DELETE 注册DELETE方法的路由
-func (group *RouterGroup) DELETE(relativePath string, handlers ...Handler) {
-	group.handle(http.MethodDelete, relativePath, handlers)
+func (group *RouterGroup) DELETE(relativePath string, handlers ...Handler) *Route {
+	return group.handle(http.MethodDelete, relativePath, handlers)
 }
 
 // PATCH 注册PATCH方法的路由
-func (group *RouterGroup) PATCH(relativePath string, handlers ...Handler) {
-	group.handle(http.MethodPatch, relativePath, handlers)
+func (group *RouterGroup) PATCH(relativePath string, handlers ...Handler) *Route {
+	return group.handle(http.MethodPatch, relativePath, handlers)
 }
 
 // PUT 注册PUT方法的路由
-func (group *RouterGroup) PUT(relativePath string, handlers ...Handler) {
-	group.handle(http.MethodPut, relativePath, handlers)
+func (group *RouterGroup) PUT(relativePath string, handlers ...Handler) *Route {
+	return group.handle(http.MethodPut, relativePath, handlers)
 }
 
 // OPTIONS 注册OPTIONS方法的路由
-func (group *RouterGroup) OPTIONS(relativePath string, handlers ...Handler) {
-	group.handle(http.MethodOptions, relativePath, handlers)
+func (group *RouterGroup) OPTIONS(relativePath string, handlers ...Handler) *Route {
+	return group.handle(http.MethodOptions, relativePath, handlers)
 }
 
 // HEAD 注册HEAD方法的路由
-func (group *RouterGroup) HEAD(relativePath string, handlers ...Handler) {
-	group.handle(http.MethodHead, relativePath, handlers)
+func (group *RouterGroup) HEAD(relativePath string, handlers ...Handler) *Route {
+	return group.handle(http.MethodHead, relativePath, handlers)
 }
 
 // Match 为一个路径同时注册多个方法的路由
@@ -183,6 +271,21 @@ func (group *RouterGroup) staticFileHandler(relativePath string, handler Handler
 	group.HEAD(relativePath, handler)
 }
 
+// Mount 将 http.Handler（包括另一个 *Engine）挂载到指定路径前缀，例如：
+// Mount("/debug", http.DefaultServeMux)
+// 前缀及其之后的所有路径的所有请求方法都交给 h 处理，h 收到的请求路径会去掉前缀（至少为"/"），
+// 原始路径可通过 OriginalPath() 获取，路由组的中间件会在 h 之前执行
+func (group *RouterGroup) Mount(relativePath string, h http.Handler) {
+	handler := func(ctx *Context) error {
+		h.ServeHTTP(ctx.ResponseWriter, stripMountPrefix(ctx.Request, ctx.PathValue(mountParam)))
+		return nil
+	}
+	finalURLPath := path.Join(relativePath, "/*"+mountParam+"?")
+	for _, method := range mountMethods {
+		group.handle(method, finalURLPath, HandlersChain{handler})
+	}
+}
+
 func (group *RouterGroup) combineHandlers(handlers HandlersChain) HandlersChain {
 	finalSize := len(group.handlers) + len(handlers)
 	mergedHandlers := make(HandlersChain, finalSize)
@@ -191,6 +294,17 @@ func (group *RouterGroup) combineHandlers(handlers HandlersChain) HandlersChain
 	return mergedHandlers
 }
 
+func (group *RouterGroup) copyMeta() map[string]any {
+	if len(group.meta) == 0 {
+		return nil
+	}
+	meta := make(map[string]any, len(group.meta))
+	for k, v := range group.meta {
+		meta[k] = v
+	}
+	return meta
+}
+
 func (group *RouterGroup) calculateAbsolutePath(relativePath string) string {
 	return joinPaths(group.basePath, relativePath)
 }
@@ -0,0 +1,111 @@
+package tsing
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// storeEntry Context 键值存储中的一个键值对
+type storeEntry struct {
+	key   any
+	value any
+}
+
+// Set 在Context中写入键值，可用于在本次会话的处理器链中传递，与 SetValue() 不同的是不会复制请求，
+// 键值保存在随 Context 复用的切片中，请求结束后清空。key 必须是可比较的类型，不能在多个goroutine中同时读写
+func (ctx *Context) Set(key, value any) {
+	if key == nil {
+		panic("nil key")
+	}
+	if !reflect.TypeOf(key).Comparable() {
+		panic("key is not comparable")
+	}
+	for i := range ctx.store {
+		if ctx.store[i].key == key {
+			ctx.store[i].value = value
+			return
+		}
+	}
+	ctx.store = append(ctx.store, storeEntry{key: key, value: value})
+}
+
+// Get 从Context中读取通过 Set() 写入的键值，并判断键是否存在
+func (ctx *Context) Get(key any) (value any, exists bool) {
+	for i := range ctx.store {
+		if ctx.store[i].key == key {
+			return ctx.store[i].value, true
+		}
+	}
+	return nil, false
+}
+
+// MustGet 从Context中读取通过 Set() 写入的键值，键不存在时触发panic
+func (ctx *Context) MustGet(key any) any {
+	if value, exists := ctx.Get(key); exists {
+		return value
+	}
+	panic(fmt.Sprintf("key %v does not exist", key))
+}
+
+// GetString 读取字符串类型的键值，键不存在或类型不匹配时返回零值
+func (ctx *Context) GetString(key any) string {
+	value, _ := Get[string](ctx, key)
+	return value
+}
+
+// GetBool 读取布尔类型的键值，键不存在或类型不匹配时返回零值
+func (ctx *Context) GetBool(key any) bool {
+	value, _ := Get[bool](ctx, key)
+	return value
+}
+
+// GetInt 读取int类型的键值，键不存在或类型不匹配时返回零值
+func (ctx *Context) GetInt(key any) int {
+	value, _ := Get[int](ctx, key)
+	return value
+}
+
+// GetInt64 读取int64类型的键值，键不存在或类型不匹配时返回零值
+func (ctx *Context) GetInt64(key any) int64 {
+	value, _ := Get[int64](ctx, key)
+	return value
+}
+
+// GetFloat64 读取float64类型的键值，键不存在或类型不匹配时返回零值
+func (ctx *Context) GetFloat64(key any) float64 {
+	value, _ := Get[float64](ctx, key)
+	return value
+}
+
+// GetDuration 读取time.Duration类型的键值，键不存在或类型不匹配时返回零值
+func (ctx *Context) GetDuration(key any) time.Duration {
+	value, _ := Get[time.Duration](ctx, key)
+	return value
+}
+
+// Get 读取通过 ctx.Set() 写入的指定类型的键值，键不存在或类型不匹配时返回零值和false，例如：
+// user, ok := tsing.Get[*User](ctx, "user")
+func Get[T any](ctx *Context, key any) (T, bool) {
+	var zero T
+	value, exists := ctx.Get(key)
+	if !exists {
+		return zero, false
+	}
+	v, ok := value.(T)
+	if !ok {
+		return zero, false
+	}
+	return v, true
+}
+
+// Value 读取键值，先查找通过 Set() 写入的键值，不存在时再从请求的 context 中查找（包括通过 SetValue() 写入的键值）
+func (ctx *Context) Value(key any) any {
+	if value, exists := ctx.Get(key); exists {
+		return value
+	}
+	if ctx.Request == nil {
+		return nil
+	}
+	return ctx.Request.Context().Value(key)
+}
@@ -0,0 +1,388 @@
+package tsing
+
+import (
+	"fmt"
+	"strings"
+)
+
+// routeTable 路由表，包含所有方法的路由树
+// 服务运行中不能直接修改正在使用的路由表，而是构建一个新的路由表后原子替换
+type routeTable struct {
+	trees       methodTrees
+	hosts       []*hostRouter // 主机名路由，未匹配主机名或主机名路由中未找到路由时使用 trees
+	names       map[string]*Route
+	maxParams   int
+	maxSections int
+}
+
+func newRouteTable() *routeTable {
+	return &routeTable{
+		trees: make(methodTrees, 0, 9),
+		names: make(map[string]*Route),
+	}
+}
+
+// addRoute 添加路由，host 不为空时将路由添加到该主机名的路由树
+func (table *routeTable) addRoute(host, method, path string, handlers HandlersChain, route *Route) error {
+	if path == "" || path[0] != '/' {
+		return fmt.Errorf("%w: path must begin with '/' in path '%s'", ErrInvalidRoute, path)
+	}
+	if method == "" {
+		return fmt.Errorf("%w: HTTP method can not be empty in path '%s'", ErrInvalidRoute, path)
+	}
+	if len(handlers) == 0 {
+		return fmt.Errorf("%w: there must be at least one handler in path '%s'", ErrInvalidRoute, path)
+	}
+
+	paths, err := expandOptional(path)
+	if err != nil {
+		return err
+	}
+
+	trees := &table.trees
+	if host != "" {
+		hostRouter, err := table.host(host)
+		if err != nil {
+			return err
+		}
+		trees = &hostRouter.trees
+	}
+
+	root := trees.get(method)
+	created := root == nil
+	if created {
+		root = new(Node)
+		root.fullPath = "/"
+		*trees = append(*trees, methodTree{method: method, root: root})
+	}
+	for k, p := range paths {
+		if err = root.addRoute(p, handlers, route); err != nil {
+			// 回滚已经注册的路径
+			for _, added := range paths[:k] {
+				root.removeRoute(added, route)
+			}
+			if created && root.handlers == nil && len(root.children) == 0 {
+				*trees = (*trees)[:len(*trees)-1]
+			}
+			return err
+		}
+	}
+
+	// 更新 maxParams
+	if paramsCount := countParams(path); paramsCount > table.maxParams {
+		table.maxParams = paramsCount
+	}
+
+	if sectionsCount := countSections(path); sectionsCount > table.maxSections {
+		table.maxSections = sectionsCount
+	}
+	return nil
+}
+
+// expandOptional 展开路径中的可选参数，返回需要注册到路由树的所有路径，从最长的路径开始
+// 可选参数必须是完整的路径段并且只能位于路径的结尾，例如 /archive/:year?/:month? 展开为
+// /archive/:year/:month、/archive/:year 和 /archive，/static/*filepath? 展开为 /static/*filepath 和 /static，
+// 根路径的 /*filepath? 只展开为 /*filepath，因为它已经可以匹配 /
+func expandOptional(path string) ([]string, error) {
+	if strings.IndexByte(path, '?') < 0 {
+		return []string{path}, nil
+	}
+
+	var (
+		buf  strings.Builder
+		cuts []int // 每个可选参数之前的'/'在展开后的路径中的位置
+		rest = path
+	)
+	for {
+		wildcard, i, _ := findWildcard(rest)
+		static := rest
+		if i >= 0 {
+			static = rest[:i]
+		}
+		if strings.IndexByte(static, '?') >= 0 {
+			return nil, fmt.Errorf("%w: '?' is only allowed after a wildcard in path '%s'", ErrInvalidRoute, path)
+		}
+		if i < 0 {
+			if len(cuts) > 0 && rest != "" {
+				return nil, fmt.Errorf("%w: optional wildcards are only allowed at the end of the path in path '%s'",
+					ErrInvalidRoute, path)
+			}
+			buf.WriteString(rest)
+			break
+		}
+
+		end := i + len(wildcard)
+		optional := false
+		if wildcard[0] == '*' && strings.HasSuffix(wildcard, "?") {
+			optional = true
+			wildcard = wildcard[:len(wildcard)-1]
+		} else if wildcard[0] == ':' && end < len(rest) && rest[end] == '?' {
+			optional = true
+			end++
+		}
+
+		switch {
+		case wildcard[0] == '*' && strings.IndexByte(wildcard, '?') >= 0:
+			return nil, fmt.Errorf("%w: '?' is only allowed after a wildcard in path '%s'", ErrInvalidRoute, path)
+		case optional:
+			if i == 0 || rest[i-1] != '/' || end < len(rest) && rest[end] != '/' {
+				return nil, fmt.Errorf("%w: optional wildcard must be a whole path segment, has: '%s' in path '%s'",
+					ErrInvalidRoute, rest[i:end], path)
+			}
+			cuts = append(cuts, buf.Len()+i-1)
+		case len(cuts) > 0:
+			return nil, fmt.Errorf("%w: optional wildcards are only allowed at the end of the path in path '%s'",
+				ErrInvalidRoute, path)
+		}
+		buf.WriteString(rest[:i])
+		buf.WriteString(wildcard)
+		rest = rest[end:]
+	}
+
+	full := buf.String()
+	paths := make([]string, 0, len(cuts)+1)
+	paths = append(paths, full)
+	for k := len(cuts) - 1; k >= 0; k-- {
+		if cuts[k] == 0 {
+			if full[1] != '*' {
+				paths = append(paths, "/")
+			}
+		} else {
+			paths = append(paths, full[:cuts[k]])
+		}
+	}
+	return paths, nil
+}
+
+// 删除路由，host 为空时删除默认的路由，删除后如果方法树为空则删除该方法树，主机名没有路由树时删除该主机名。
+// route 带有匹配条件时只删除该路由，否则删除路径的默认路由，路径中其它带有匹配条件的路由都会保留
+func (table *routeTable) removeRoute(host, method, path string, route *Route) bool {
+	paths, err := expandOptional(path)
+	if err != nil {
+		return false
+	}
+	trees := &table.trees
+	hostIndex := -1
+	if host != "" {
+		host = normalizeHost(host)
+		for i := range table.hosts {
+			if table.hosts[i].pattern == host {
+				hostIndex = i
+				trees = &table.hosts[i].trees
+				break
+			}
+		}
+		if hostIndex < 0 {
+			return false
+		}
+	}
+
+	for i := range *trees {
+		if (*trees)[i].method != method {
+			continue
+		}
+		root := (*trees)[i].root
+		for _, p := range paths {
+			if !root.removeRoute(p, route) {
+				return false
+			}
+		}
+		if root.handlers == nil && len(root.children) == 0 {
+			*trees = append((*trees)[:i:i], (*trees)[i+1:]...)
+		}
+		if hostIndex >= 0 && len(*trees) == 0 {
+			table.hosts = append(table.hosts[:hostIndex:hostIndex], table.hosts[hostIndex+1:]...)
+		}
+
+		for name, named := range table.names {
+			if named.Method != method || named.Path != path || normalizeHost(named.Host) != host {
+				continue
+			}
+			if named == route || route == nil && len(named.predicates) == 0 {
+				delete(table.names, name)
+			}
+		}
+		table.updateMaxCount()
+		return true
+	}
+	return false
+}
+
+// 根据剩余的路由重新计算 maxParams 和 maxSections
+func (table *routeTable) updateMaxCount() {
+	table.maxParams = 0
+	table.maxSections = 0
+	table.eachTree(func(_ string, tree methodTree) {
+		tree.root.iterate(func(n *Node) {
+			if paramsCount := countParams(n.fullPath); paramsCount > table.maxParams {
+				table.maxParams = paramsCount
+			}
+			if sectionsCount := countSections(n.fullPath); sectionsCount > table.maxSections {
+				table.maxSections = sectionsCount
+			}
+		})
+	})
+}
+
+// eachTree 遍历默认的路由树和所有主机名的路由树
+func (table *routeTable) eachTree(fn func(host string, tree methodTree)) {
+	for _, tree := range table.trees {
+		fn("", tree)
+	}
+	for _, host := range table.hosts {
+		for _, tree := range host.trees {
+			fn(host.pattern, tree)
+		}
+	}
+}
+
+// 复制路由表，路由树会被深度复制，以便修改副本时不影响正在使用的路由表
+func (table *routeTable) clone() *routeTable {
+	c := &routeTable{
+		hosts:       make([]*hostRouter, len(table.hosts)),
+		names:       make(map[string]*Route, len(table.names)),
+		maxParams:   table.maxParams,
+		maxSections: table.maxSections,
+	}
+	c.trees = cloneTrees(table.trees)
+	for i, host := range table.hosts {
+		hostCopy := *host
+		hostCopy.trees = cloneTrees(host.trees)
+		c.hosts[i] = &hostCopy
+	}
+	for name, route := range table.names {
+		c.names[name] = route
+	}
+	return c
+}
+
+// cloneTrees 深度复制路由树，路由对象仍与原路由表共用
+func cloneTrees(trees methodTrees) methodTrees {
+	c := make(methodTrees, len(trees), cap(trees))
+	for i, tree := range trees {
+		c[i] = methodTree{method: tree.method, root: tree.root.clone()}
+	}
+	return c
+}
+
+// setName 在路由表中设置路由的名称，名称已被其它路由使用时触发panic
+func (table *routeTable) setName(route *Route, name string) {
+	if existing, exist := table.names[name]; exist && existing != route {
+		panic("route name '" + name + "' is already used by '" + existing.Method + " " + existing.Path + "'")
+	}
+	if route.name != "" && table.names[route.name] == route {
+		delete(table.names, route.name)
+	}
+	route.name = name
+	table.names[name] = route
+}
+
+// eachRoute 遍历路由表中的所有路由
+func (table *routeTable) eachRoute(fn func(route *Route)) {
+	table.eachTree(func(_ string, tree methodTree) {
+		tree.root.iterate(func(n *Node) {
+			if n.route != nil {
+				fn(n.route)
+			}
+			for _, route := range n.routes {
+				fn(route)
+			}
+		})
+	})
+}
+
+// adoptRoutes 将路由表中的路由改为属于 table，之后设置的路由名称才会写入该路由表，
+// 路由对象与旧的路由表共用，所以只能在 table 替换为当前的路由表之后调用
+func (table *routeTable) adoptRoutes() {
+	table.eachRoute(func(route *Route) {
+		route.table = table
+	})
+}
+
+// addRoute 向当前的路由表注册路由，开始处理请求之前直接修改路由表，
+// 之后与 RemoveRoute 相同，复制当前的路由表并在副本中注册，然后原子替换
+func (engine *Engine) addRoute(route *Route) error {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	table := engine.table.Load()
+	serving := engine.serving.Load()
+	if serving {
+		table = table.clone()
+	}
+	route.table = table
+	if err := table.addRoute(route.Host, route.Method, route.Path, route.handlers, route); err != nil {
+		return err
+	}
+	if serving {
+		engine.table.Store(table)
+		table.adoptRoutes()
+	}
+	return nil
+}
+
+// RemoveRoute 删除路由，path 必须是注册路由时的完整路径（包括参数），例如 "/users/:id"
+// 删除时会复制当前的路由表，在副本中删除路由并压缩路由树后原子替换，不会阻塞正在处理的请求
+func (engine *Engine) RemoveRoute(method, path string) error {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	table := engine.table.Load().clone()
+	if !table.removeRoute("", method, path, nil) {
+		return fmt.Errorf("%w: %s %s", ErrRouteNotFound, method, path)
+	}
+	engine.table.Store(table)
+	table.adoptRoutes()
+	return nil
+}
+
+// RemoveHostRoute 删除通过 Host() 注册的主机名路由，host 必须是注册时的主机名，例如 ":tenant.example.com"，
+// 删除方式与 RemoveRoute 相同
+func (engine *Engine) RemoveHostRoute(host, method, path string) error {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	table := engine.table.Load().clone()
+	if !table.removeRoute(host, method, path, nil) {
+		return fmt.Errorf("%w: %s %s %s", ErrRouteNotFound, host, method, path)
+	}
+	engine.table.Store(table)
+	table.adoptRoutes()
+	return nil
+}
+
+// Reload 构建一个新的路由表，在 fn 中注册的所有路由完成后原子替换当前的路由表，
+// 替换时不会阻塞请求，正在处理的请求仍然使用旧的路由表完成，之后的请求使用新的路由表。
+// fn 中注册的路由会继承引擎通过 Use() 注册的中间件，
+// 如果 fn 返回错误或触发了panic，则放弃新的路由表并返回错误，当前的路由表不受影响
+func (engine *Engine) Reload(fn func(r Router) error) (err error) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	table := newRouteTable()
+	defer func() {
+		if v := recover(); v != nil {
+			if e, ok := v.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("%v", v)
+			}
+		}
+	}()
+
+	if err = fn(&RouterGroup{
+		handlers: engine.RouterGroup.handlers,
+		basePath: engine.RouterGroup.basePath,
+		engine:   engine,
+		table:    table,
+		root:     true,
+	}); err != nil {
+		return err
+	}
+
+	table.eachRoute(func(route *Route) {
+		route.building = false
+	})
+	engine.table.Store(table)
+	return nil
+}
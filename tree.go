@@ -1,7 +1,10 @@
 package tsing
 
 import (
+	"fmt"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 var (
@@ -71,14 +74,17 @@ const (
 )
 
 type Node struct {
-	path      string
-	indices   string
-	wildChild bool
-	nType     nodeType
-	priority  uint32
-	children  []*Node // child nodes, at most 1 :paramNode style Node at the end of the array
-	handlers  HandlersChain
-	fullPath  string
+	path       string
+	indices    string
+	wildChild  bool
+	nType      nodeType
+	priority   uint32
+	children   []*Node // child nodes, at most 1 :paramNode style Node at the end of the array
+	handlers   HandlersChain
+	route      *Route
+	routes     []*Route         // 带有匹配条件的路由，按注册顺序匹配
+	constraint *paramConstraint // paramNode 的参数约束
+	fullPath   string
 }
 
 // Increments priority of the given child and reorders if necessary
@@ -104,15 +110,21 @@ func (n *Node) incrementChildPrio(pos int) int {
 	return newPos
 }
 
-func (n *Node) addRoute(path string, handlers HandlersChain) {
+func (n *Node) addRoute(path string, handlers HandlersChain, route *Route) error {
+	if err := checkWildcards(path); err != nil {
+		return err
+	}
+
 	fullPath := path
 	n.priority++
 
 	// 如果是空树
 	if len(n.path) == 0 && len(n.children) == 0 {
-		n.insertChild(path, fullPath, handlers)
+		if err := n.insertChild(path, fullPath, handlers, route); err != nil {
+			return err
+		}
 		n.nType = rootNode
-		return
+		return nil
 	}
 
 	parentFullPathIndex := 0
@@ -124,20 +136,25 @@ walk:
 
 		if i < len(n.path) {
 			child := Node{
-				path:      n.path[i:],
-				wildChild: n.wildChild,
-				nType:     staticNode,
-				indices:   n.indices,
-				children:  n.children,
-				handlers:  n.handlers,
-				priority:  n.priority - 1,
-				fullPath:  n.fullPath,
+				path:       n.path[i:],
+				wildChild:  n.wildChild,
+				nType:      staticNode,
+				indices:    n.indices,
+				children:   n.children,
+				handlers:   n.handlers,
+				route:      n.route,
+				routes:     n.routes,
+				constraint: n.constraint,
+				priority:   n.priority - 1,
+				fullPath:   n.fullPath,
 			}
 
 			n.children = []*Node{&child}
 			n.indices = bytesToStr([]byte{n.path[i]})
 			n.path = path[:i]
 			n.handlers = nil
+			n.route = nil
+			n.routes = nil
 			n.wildChild = false
 			n.fullPath = fullPath[:parentFullPathIndex+i]
 		}
@@ -147,7 +164,7 @@ walk:
 			path = path[i:]
 			c := path[0]
 
-			if n.nType == paramNode && c == '/' && len(n.children) == 1 {
+			if n.nType == paramNode && c != ':' && c != '*' && len(n.children) == 1 {
 				parentFullPathIndex += len(n.path)
 				n = n.children[0]
 				n.priority++
@@ -176,9 +193,8 @@ walk:
 				n = n.children[len(n.children)-1]
 				n.priority++
 
-				if len(path) >= len(n.path) && n.path == path[:len(n.path)] &&
-					n.nType != wildcardNode &&
-					(len(n.path) >= len(path) || path[len(n.path)] == '/') {
+				// Check if the wildcard matches
+				if wildcard, _, _ := findWildcard(path); wildcard == n.path && n.nType != wildcardNode {
 					continue walk
 				}
 
@@ -187,28 +203,148 @@ walk:
 					pathSeg = strings.SplitN(pathSeg, "/", 2)[0]
 				}
 				prefix := fullPath[:strings.Index(fullPath, pathSeg)] + n.path //nolint:gocritic
-				panic("'" + pathSeg +
-					"' in new path '" + fullPath +
-					"' conflicts with existing wildcard '" + n.path +
-					"' in existing prefix '" + prefix +
-					"'")
+				return &ErrRouteConflict{
+					Path:     fullPath,
+					Segment:  pathSeg,
+					Existing: prefix,
+				}
 			}
 
-			n.insertChild(path, fullPath, handlers)
-			return
+			return n.insertChild(path, fullPath, handlers, route)
 		}
 
 		// 为当前节点添加处理器
-		if n.handlers != nil {
-			panic("handlers are already registered for path '" + fullPath + "'")
+		if n.hasDefaultRoute() && (route == nil || len(route.predicates) == 0) {
+			return fmt.Errorf("%w for path '%s'", ErrDuplicateRoute, fullPath)
 		}
-		n.handlers = handlers
+		n.setRoute(handlers, route)
 		n.fullPath = fullPath
-		return
+		return nil
+	}
+}
+
+// removeRoute removes a route registered with the given path and compacts the tree on
+// the way back. The path must be the same as the one used for addRoute, including the
+// wildcards. If route has predicates only that route is removed, otherwise the default
+// route of the path is removed; the predicate routes of the path are kept in both cases.
+// Returns false if there is no such route registered with the path.
+func (n *Node) removeRoute(path string, route *Route) bool {
+	if len(path) < len(n.path) || path[:len(n.path)] != n.path {
+		return false
+	}
+	path = path[len(n.path):]
+
+	if path == "" {
+		if !n.unsetRoute(route) {
+			return false
+		}
+	} else {
+		i := n.childIndex(path)
+		if i < 0 || !n.children[i].removeRoute(path, route) {
+			return false
+		}
+		// Drop the child if nothing is left below it
+		if child := n.children[i]; child.handlers == nil && len(child.children) == 0 {
+			n.removeChild(i)
+		}
+	}
+
+	n.compact()
+	return true
+}
+
+// childIndex returns the position of the child that the registered path continues with,
+// or -1 if there is no such child
+func (n *Node) childIndex(path string) int {
+	for i, c := range []byte(n.indices) {
+		if c == path[0] {
+			return i
+		}
+	}
+	if n.wildChild {
+		return len(n.children) - 1
+	}
+	// The child of a paramNode is not indexed
+	if n.nType == paramNode && len(n.children) == 1 {
+		return 0
+	}
+	return -1
+}
+
+// removeChild removes the child at the given position together with its index char
+func (n *Node) removeChild(i int) {
+	if n.wildChild && i == len(n.children)-1 {
+		n.wildChild = false
+	} else if i < len(n.indices) {
+		n.indices = n.indices[:i] + n.indices[i+1:]
+	}
+	n.children = append(n.children[:i:i], n.children[i+1:]...)
+}
+
+// compact merges a static Node without handlers with its only static child,
+// then recalculates the priority and reorders the children by priority
+func (n *Node) compact() {
+	if n.handlers == nil && !n.wildChild && len(n.children) == 1 &&
+		(n.nType == staticNode || n.nType == rootNode) && n.children[0].nType == staticNode {
+		child := n.children[0]
+		n.path += child.path
+		n.indices = child.indices
+		n.wildChild = child.wildChild
+		n.children = child.children
+		n.handlers = child.handlers
+		n.route = child.route
+		n.routes = child.routes
+		n.fullPath = child.fullPath
+	}
+
+	n.priority = 0
+	if n.handlers != nil {
+		n.priority = 1
+	}
+	for _, child := range n.children {
+		n.priority += child.priority
+	}
+
+	// The indexed children come first, the wildcard child always stays at the end
+	indices := []byte(n.indices)
+	for i := 1; i < len(indices); i++ {
+		for j := i; j > 0 && n.children[j-1].priority < n.children[j].priority; j-- {
+			n.children[j-1], n.children[j] = n.children[j], n.children[j-1]
+			indices[j-1], indices[j] = indices[j], indices[j-1]
+		}
+	}
+	n.indices = bytesToStr(indices)
+}
+
+// clone returns a deep copy of the tree, the handlers are shared
+func (n *Node) clone() *Node {
+	c := *n
+	if n.routes != nil {
+		c.routes = append([]*Route(nil), n.routes...)
+	}
+	if n.children != nil {
+		c.children = make([]*Node, len(n.children))
+		for i, child := range n.children {
+			c.children[i] = child.clone()
+		}
+	}
+	return &c
+}
+
+// iterate calls fn for every Node which has handlers registered, depth-first
+func (n *Node) iterate(fn func(n *Node)) {
+	if n.handlers != nil {
+		fn(n)
+	}
+	for _, child := range n.children {
+		child.iterate(fn)
 	}
 }
 
 // Search for a wildcard segment and check the name for invalid characters.
+// The name of a paramNode ends at the first byte which is not allowed in names, so that
+// static text or another wildcard may follow it in the same segment, e.g. /:name.:ext.
+// The paramNode may end with a constraint like :id<int>, which is skipped as a whole.
 // Returns -1 as index, if no wildcard was found.
 func findWildcard(path string) (wildcard string, i int, valid bool) {
 	// 查找起始
@@ -217,12 +353,28 @@ func findWildcard(path string) (wildcard string, i int, valid bool) {
 			continue
 		}
 
-		// 查找结尾并检查无效字符
 		valid = true
-		for end, c := range []byte(path[start+1:]) {
-			switch c {
+		if c == ':' {
+			end := start + 1
+			for end < len(path) && isNameChar(path[end]) {
+				end++
+			}
+			if end < len(path) && path[end] == '<' {
+				// 跳过约束表达式
+				length := constraintEnd(path[end:])
+				if length < 0 {
+					return path[start:], start, false
+				}
+				end += length + 1
+			}
+			return path[start:end], start, valid
+		}
+
+		// 查找 catch-all 的结尾并检查无效字符
+		for end := start + 1; end < len(path); end++ {
+			switch path[end] {
 			case '/':
-				return path[start : start+1+end], start, valid
+				return path[start:end], start, valid
 			case ':', '*':
 				valid = false
 			}
@@ -232,22 +384,72 @@ func findWildcard(path string) (wildcard string, i int, valid bool) {
 	return "", -1, false
 }
 
-func (n *Node) insertChild(path string, fullPath string, handlers HandlersChain) {
+// isNameChar reports whether c is allowed in the name of a wildcard
+func isNameChar(c byte) bool {
+	return c >= 0x80 || c == '_' || c == '-' ||
+		'0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// checkWildcards validates all wildcards of the path before the tree is modified,
+// so that a failed registration doesn't leave a half inserted route behind
+func checkWildcards(path string) error {
+	fullPath := path
 	for {
-		// Find prefix until first wildcard
 		wildcard, i, valid := findWildcard(path)
-		if i < 0 { // No wildcard found
-			break
+		if i < 0 {
+			return nil
 		}
 
-		// The wildcard name must only contain one ':' or '*' character
 		if !valid {
-			panic("only one wildcard per path segment is allowed, has: '" +
-				wildcard + "' in path '" + fullPath + "'")
+			if wildcard[0] == ':' {
+				return fmt.Errorf("%w: constraint must be closed with '>', has: '%s' in path '%s'",
+					ErrInvalidRoute, wildcard, fullPath)
+			}
+			// The catch-all name must only contain one '*' character
+			return fmt.Errorf("%w: only one wildcard per path segment is allowed, has: '%s' in path '%s'",
+				ErrInvalidRoute, wildcard, fullPath)
+		}
+
+		name, expr := splitWildcard(wildcard)
+		if name == "" {
+			return fmt.Errorf("%w: wildcards must be named with a non-empty name in path '%s'", ErrInvalidRoute, fullPath)
+		}
+
+		if expr != "" || strings.HasSuffix(wildcard, "<>") {
+			if wildcard[0] == '*' {
+				return fmt.Errorf("%w: catch-all can not have a constraint in path '%s'", ErrInvalidRoute, fullPath)
+			}
+			if _, err := compileConstraint(expr); err != nil {
+				return fmt.Errorf("%w: invalid constraint '%s' in path '%s': %v", ErrInvalidRoute, expr, fullPath, err)
+			}
+		}
+
+		if wildcard[0] == '*' {
+			if i+len(wildcard) != len(path) {
+				return fmt.Errorf("%w: catch-all routes are only allowed at the end of the path in path '%s'",
+					ErrInvalidRoute, fullPath)
+			}
+			if i == 0 || path[i-1] != '/' {
+				return fmt.Errorf("%w: no / before catch-all in path '%s'", ErrInvalidRoute, fullPath)
+			}
+		}
+
+		path = path[i+len(wildcard):]
+
+		// Two wildcards in a segment must be separated by static text
+		if len(path) > 0 && (path[0] == ':' || path[0] == '*') {
+			return fmt.Errorf("%w: wildcards must be separated by static text, has: '%s' in path '%s'",
+				ErrInvalidRoute, wildcard+path[:1], fullPath)
 		}
+	}
+}
 
-		if len(wildcard) < 2 {
-			panic("wildcards must be named with a non-empty name in path '" + fullPath + "'")
+func (n *Node) insertChild(path string, fullPath string, handlers HandlersChain, route *Route) error {
+	for {
+		// Find prefix until first wildcard, the wildcards have been validated by checkWildcards
+		wildcard, i, _ := findWildcard(path)
+		if i < 0 { // No wildcard found
+			break
 		}
 
 		if wildcard[0] == ':' { // paramNode
@@ -262,13 +464,17 @@ func (n *Node) insertChild(path string, fullPath string, handlers HandlersChain)
 				path:     wildcard,
 				fullPath: fullPath,
 			}
+			if name, expr := splitWildcard(wildcard); expr != "" {
+				match, _ := compileConstraint(expr) // 已经由 checkWildcards 检查过
+				child.constraint = &paramConstraint{name: name, match: match}
+			}
 			n.addChild(child)
 			n.wildChild = true
 			n = child
 			n.priority++
 
 			// if the path doesn't end with the wildcard, then there
-			// will be another subpath starting with '/'
+			// will be another subpath starting with '/' or static text of the same segment
 			if len(wildcard) < len(path) {
 				path = path[len(wildcard):]
 
@@ -282,28 +488,24 @@ func (n *Node) insertChild(path string, fullPath string, handlers HandlersChain)
 			}
 
 			// Otherwise we're done. Insert the handle in the new leaf
-			n.handlers = handlers
-			return
+			n.setRoute(handlers, route)
+			return nil
 		}
 
 		// wildcardNode
-		if i+len(wildcard) != len(path) {
-			panic("catch-all routes are only allowed at the end of the path in path '" + fullPath + "'")
-		}
-
 		if len(n.path) > 0 && n.path[len(n.path)-1] == '/' {
 			pathSeg := strings.SplitN(n.children[0].path, "/", 2)[0]
-			panic("catch-all wildcard '" + path +
-				"' in new path '" + fullPath +
-				"' conflicts with existing path segment '" + pathSeg +
-				"' in existing prefix '" + n.path + pathSeg +
-				"'")
+			return &ErrRouteConflict{
+				Path:     fullPath,
+				Segment:  path,
+				Existing: n.path + pathSeg,
+			}
 		}
 
 		// currently fixed width 1 for '/'
 		i--
-		if path[i] != '/' {
-			panic("no / before catch-all in path '" + fullPath + "'")
+		if i < 0 || path[i] != '/' {
+			return fmt.Errorf("%w: no / before catch-all in path '%s'", ErrInvalidRoute, fullPath)
 		}
 
 		n.path = path[:i]
@@ -324,24 +526,122 @@ func (n *Node) insertChild(path string, fullPath string, handlers HandlersChain)
 		child = &Node{
 			path:     path[i:],
 			nType:    wildcardNode,
-			handlers: handlers,
 			priority: 1,
 			fullPath: fullPath,
 		}
+		child.setRoute(handlers, route)
 		n.children = []*Node{child}
 
-		return
+		return nil
 	}
 
 	// If no wildcard was found, simply insert the path and handle
 	n.path = path
-	n.handlers = handlers
+	n.setRoute(handlers, route)
 	n.fullPath = fullPath
+	return nil
+}
+
+// setRoute sets the handlers of the Node. A route with predicates is appended to the routes
+// of the Node, the handlers of the first one only mark the Node as a leaf if there is no default route.
+func (n *Node) setRoute(handlers HandlersChain, route *Route) {
+	if route != nil && len(route.predicates) > 0 {
+		n.routes = append(n.routes, route)
+		if n.handlers == nil {
+			n.handlers = handlers
+		}
+		return
+	}
+	n.handlers = handlers
+	n.route = route
+}
+
+// unsetRoute removes route from the Node if it has predicates, otherwise the default route.
+// The Node stays a leaf as long as any route is left.
+func (n *Node) unsetRoute(route *Route) bool {
+	if route != nil && len(route.predicates) > 0 {
+		i := 0
+		for i < len(n.routes) && n.routes[i] != route {
+			i++
+		}
+		if i == len(n.routes) {
+			return false
+		}
+		n.routes = append(n.routes[:i:i], n.routes[i+1:]...)
+	} else {
+		if !n.hasDefaultRoute() || route != nil && n.route != route {
+			return false
+		}
+		n.route = nil
+	}
+
+	switch {
+	case n.route != nil:
+	case len(n.routes) > 0:
+		n.handlers = n.routes[0].handlers
+	default:
+		n.handlers = nil
+		n.routes = nil
+	}
+	return true
+}
+
+// hasDefaultRoute reports whether the Node has a route without predicates
+func (n *Node) hasDefaultRoute() bool {
+	return n.handlers != nil && (n.route != nil || len(n.routes) == 0)
+}
+
+// paramEnd returns the end of the paramNode value at the beginning of path. The value ends
+// at the first position from `from` on where the static text following the paramNode in
+// the same segment matches, or at the next '/' or the path end, so /:name.json matches
+// /a.b.json with name=a.b. A value ending at the static text is never empty.
+// If the lookup fails after that end, the caller retries from end+1 until the segment end.
+func (n *Node) paramEnd(path string, from int, foldCase bool) int {
+	end := strings.IndexByte(path, '/')
+	if end < 0 {
+		end = len(path)
+	}
+	if len(n.children) == 0 {
+		return end
+	}
+
+	child := n.children[0]
+	if child.path != "" && child.path[0] == '/' {
+		return end
+	}
+	for i := max(from, 1); i < end; i++ {
+		if child.path != "" {
+			if hasPrefix(path[i:], child.path, foldCase) {
+				return i
+			}
+			continue
+		}
+		// The static text has been split, try every child
+		for j := 0; j < len(child.indices); j++ {
+			if child.indices[j] != '/' && hasPrefix(path[i:], child.children[j].path, foldCase) {
+				return i
+			}
+		}
+	}
+	return end
+}
+
+// hasPrefix reports whether s begins with prefix, optionally ignoring the case
+func hasPrefix(s, prefix string, foldCase bool) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	if foldCase {
+		return strings.EqualFold(s[:len(prefix)], prefix)
+	}
+	return s[:len(prefix)] == prefix
 }
 
 // nodeValue holds return values of (*Node).getValue method
 type nodeValue struct {
 	handlers HandlersChain
+	route    *Route
+	routes   []*Route
 	params   *Params
 	tsr      bool
 	fullPath string
@@ -351,6 +651,7 @@ type skippedNode struct {
 	path        string
 	node        *Node
 	paramsCount int16
+	paramFrom   int // where to continue searching the end of the paramNode value, 0 for static nodes
 }
 
 // Returns the handle registered with the given path (key). The values of
@@ -359,7 +660,10 @@ type skippedNode struct {
 // made if a handle exists with an extra (without the) trailing slash for the
 // given path.
 func (n *Node) getValue(path string, params *Params, skippedNodes *[]skippedNode) (value nodeValue) {
-	var globalParamsCount int16
+	var (
+		globalParamsCount int16
+		paramFrom         int
+	)
 
 walk: // Outer loop for walking the tree
 	for {
@@ -379,13 +683,16 @@ walk: // Outer loop for walking the tree
 							(*skippedNodes)[index] = skippedNode{
 								path: prefix + path,
 								node: &Node{
-									path:      n.path,
-									wildChild: n.wildChild,
-									nType:     n.nType,
-									priority:  n.priority,
-									children:  n.children,
-									handlers:  n.handlers,
-									fullPath:  n.fullPath,
+									path:       n.path,
+									wildChild:  n.wildChild,
+									nType:      n.nType,
+									priority:   n.priority,
+									children:   n.children,
+									handlers:   n.handlers,
+									route:      n.route,
+									routes:     n.routes,
+									constraint: n.constraint,
+									fullPath:   n.fullPath,
 								},
 								paramsCount: globalParamsCount,
 							}
@@ -410,6 +717,7 @@ walk: // Outer loop for walking the tree
 									*value.params = (*value.params)[:skippedNode.paramsCount]
 								}
 								globalParamsCount = skippedNode.paramsCount
+								paramFrom = skippedNode.paramFrom
 								continue walk
 							}
 						}
@@ -423,6 +731,7 @@ walk: // Outer loop for walking the tree
 				}
 
 				// Handle wildcard child, which is always at the end of the array
+				parent := n
 				n = n.children[len(n.children)-1]
 				globalParamsCount++
 
@@ -431,10 +740,49 @@ walk: // Outer loop for walking the tree
 					// fix truncate the parameter
 					// tree_test.go  line: 204
 
-					// Find paramNode end (either '/' or path end)
-					end := 0
-					for end < len(path) && path[end] != '/' {
-						end++
+					// Find paramNode end (either '/', the static text after it or path end)
+					end := n.paramEnd(path, paramFrom, false)
+					paramFrom = 0
+
+					// The value ends at the static text, so it may also end at the next match
+					// or the segment end, if the lookup fails after this end
+					if end < len(path) && path[end] != '/' {
+						*skippedNodes = append(*skippedNodes, skippedNode{
+							path: prefix + path,
+							node: &Node{
+								path:      parent.path,
+								wildChild: parent.wildChild,
+								nType:     parent.nType,
+								priority:  parent.priority,
+								children:  parent.children,
+								fullPath:  parent.fullPath,
+							},
+							paramsCount: globalParamsCount - 1,
+							paramFrom:   end + 1,
+						})
+					}
+
+					key := n.path[1:]
+					if n.constraint != nil {
+						// The value doesn't satisfy the constraint, roll back to last valid skippedNode
+						if !n.constraint.match(path[:end]) {
+							for length := len(*skippedNodes); length > 0; length-- {
+								skippedNode := (*skippedNodes)[length-1]
+								*skippedNodes = (*skippedNodes)[:length-1]
+								if strings.HasSuffix(skippedNode.path, path) {
+									path = skippedNode.path
+									n = skippedNode.node
+									if value.params != nil {
+										*value.params = (*value.params)[:skippedNode.paramsCount]
+									}
+									globalParamsCount = skippedNode.paramsCount
+									paramFrom = skippedNode.paramFrom
+									continue walk
+								}
+							}
+							return
+						}
+						key = n.constraint.name
 					}
 
 					// Save paramNode value
@@ -447,7 +795,7 @@ walk: // Outer loop for walking the tree
 						*value.params = (*value.params)[:i+1]
 						val := path[:end]
 						(*value.params)[i] = Param{
-							Key:   n.path[1:],
+							Key:   key,
 							Value: val,
 						}
 					}
@@ -467,8 +815,25 @@ walk: // Outer loop for walking the tree
 
 					if value.handlers = n.handlers; value.handlers != nil {
 						value.fullPath = n.fullPath
+						value.route = n.route
+						value.routes = n.routes
 						return
 					}
+					// roll back to last valid skippedNode
+					for length := len(*skippedNodes); length > 0; length-- {
+						skippedNode := (*skippedNodes)[length-1]
+						*skippedNodes = (*skippedNodes)[:length-1]
+						if strings.HasSuffix(skippedNode.path, path) {
+							path = skippedNode.path
+							n = skippedNode.node
+							if value.params != nil {
+								*value.params = (*value.params)[:skippedNode.paramsCount]
+							}
+							globalParamsCount = skippedNode.paramsCount
+							paramFrom = skippedNode.paramFrom
+							continue walk
+						}
+					}
 					if len(n.children) == 1 {
 						// No handle found. Check if a handle for this path + a
 						// trailing slash exists for TSR recommendation
@@ -495,6 +860,8 @@ walk: // Outer loop for walking the tree
 
 					value.handlers = n.handlers
 					value.fullPath = n.fullPath
+					value.route = n.route
+					value.routes = n.routes
 					return
 
 				default:
@@ -517,6 +884,7 @@ walk: // Outer loop for walking the tree
 							*value.params = (*value.params)[:skippedNode.paramsCount]
 						}
 						globalParamsCount = skippedNode.paramsCount
+						paramFrom = skippedNode.paramFrom
 						continue walk
 					}
 				}
@@ -526,6 +894,8 @@ walk: // Outer loop for walking the tree
 			// Check if this Node has a handle registered.
 			if value.handlers = n.handlers; value.handlers != nil {
 				value.fullPath = n.fullPath
+				value.route = n.route
+				value.routes = n.routes
 				return
 			}
 
@@ -573,6 +943,7 @@ walk: // Outer loop for walking the tree
 						*value.params = (*value.params)[:skippedNode.paramsCount]
 					}
 					globalParamsCount = skippedNode.paramsCount
+					paramFrom = skippedNode.paramFrom
 					continue walk
 				}
 			}
@@ -580,3 +951,211 @@ walk: // Outer loop for walking the tree
 		return
 	}
 }
+
+// Makes a case-insensitive lookup of the given path and tries to find a handler.
+// It can optionally also fix trailing slashes.
+// It returns the case-corrected path and a bool indicating whether the lookup
+// was successful.
+func (n *Node) findCaseInsensitivePath(path string, fixTrailingSlash bool) ([]byte, bool) {
+	const stackBufSize = 128
+
+	// Use a static sized buffer on the stack in the common case.
+	// If the path is too long, allocate a buffer on the heap instead.
+	buf := make([]byte, 0, stackBufSize)
+	if length := len(path) + 1; length > stackBufSize {
+		buf = make([]byte, 0, length)
+	}
+
+	ciPath := n.findCaseInsensitivePathRec(path, buf, [4]byte{}, fixTrailingSlash)
+	return ciPath, ciPath != nil
+}
+
+// Shift bytes in array by n bytes left
+func shiftNRuneBytes(rb [4]byte, n int) [4]byte {
+	switch n {
+	case 0:
+		return rb
+	case 1:
+		return [4]byte{rb[1], rb[2], rb[3], 0}
+	case 2:
+		return [4]byte{rb[2], rb[3]}
+	case 3:
+		return [4]byte{rb[3]}
+	default:
+		return [4]byte{}
+	}
+}
+
+// Recursive case-insensitive lookup function used by n.findCaseInsensitivePath.
+// Static children are tried before the wildcard child, so that the lookup
+// falls back the same way as getValue does.
+func (n *Node) findCaseInsensitivePathRec(path string, ciPath []byte, rb [4]byte, fixTrailingSlash bool) []byte {
+	npLen := len(n.path)
+
+	// The first byte has already been matched by the parent's indices
+	if len(path) < npLen || (npLen > 1 && !strings.EqualFold(path[1:npLen], n.path[1:])) {
+		// Nothing found. Try to fix the path by adding a trailing slash
+		if fixTrailingSlash && len(path) > 0 && len(path)+1 == npLen && n.path[len(path)] == '/' &&
+			strings.EqualFold(path[1:], n.path[1:len(path)]) && n.handlers != nil {
+			return append(ciPath, n.path...)
+		}
+		return nil
+	}
+
+	// Add common prefix to result
+	oldPath := path
+	path = path[npLen:]
+	ciPath = append(ciPath, n.path...)
+
+	if len(path) == 0 {
+		// We should have reached the Node containing the handle.
+		// Check if this Node has a handle registered.
+		if n.handlers != nil {
+			return ciPath
+		}
+
+		// No handle found.
+		// Try to fix the path by adding a trailing slash
+		if fixTrailingSlash {
+			for i, c := range []byte(n.indices) {
+				if c == '/' {
+					child := n.children[i]
+					if (len(child.path) == 1 && child.handlers != nil) ||
+						(child.nType == wildcardNode && child.children[0].handlers != nil) {
+						return append(ciPath, '/')
+					}
+					return nil
+				}
+			}
+		}
+		return nil
+	}
+
+	// Try all the non-wildcard children first
+	if len(n.indices) > 0 {
+		// Skip rune bytes already processed
+		rb = shiftNRuneBytes(rb, npLen)
+
+		if rb[0] != 0 {
+			// Old rune not finished
+			if out := n.findCaseInsensitiveChild(rb[0], path, ciPath, rb, fixTrailingSlash); out != nil {
+				return out
+			}
+		} else {
+			// Process a new rune
+			var rv rune
+
+			// Find rune start.
+			// Runes are up to 4 byte long, so the start is at most 3 bytes back
+			var off int
+			for maxOff := minNumber(npLen, 3); off <= maxOff; off++ {
+				if i := npLen - off; utf8.RuneStart(oldPath[i]) {
+					// read rune from cached path
+					rv, _ = utf8.DecodeRuneInString(oldPath[i:])
+					break
+				}
+			}
+
+			// Calculate lowercase bytes of current rune
+			lo := unicode.ToLower(rv)
+			rb = [4]byte{}
+			utf8.EncodeRune(rb[:], lo)
+
+			// Skip already processed bytes
+			rb = shiftNRuneBytes(rb, off)
+
+			// Both the uppercase byte and the lowercase byte might exist as an index
+			if out := n.findCaseInsensitiveChild(rb[0], path, ciPath, rb, fixTrailingSlash); out != nil {
+				return out
+			}
+
+			// If we found no match, the same for the uppercase rune, if it differs
+			if up := unicode.ToUpper(rv); up != lo {
+				rb = [4]byte{}
+				utf8.EncodeRune(rb[:], up)
+				rb = shiftNRuneBytes(rb, off)
+
+				if out := n.findCaseInsensitiveChild(rb[0], path, ciPath, rb, fixTrailingSlash); out != nil {
+					return out
+				}
+			}
+		}
+	}
+
+	// Handle wildcard child, which is always at the end of the array
+	if n.wildChild {
+		child := n.children[len(n.children)-1]
+
+		switch child.nType { //nolint:exhaustive
+		case paramNode:
+			// Try every end of the paramNode value, like getValue does when it rolls back:
+			// each match of the static text after it, then '/' or the path end
+			for end := child.paramEnd(path, 1, true); ; end = child.paramEnd(path, end+1, true) {
+				if out := child.findCaseInsensitiveParam(path, end, ciPath, fixTrailingSlash); out != nil {
+					return out
+				}
+				if end == len(path) || path[end] == '/' {
+					break
+				}
+			}
+
+		case wildcardNode:
+			return append(ciPath, path...)
+
+		default:
+			panic("invalid Node type")
+		}
+	}
+
+	// Nothing found. We can recommend to redirect to the same URL
+	// without a trailing slash if a leaf exists for that path
+	if fixTrailingSlash && path == "/" && n.handlers != nil {
+		return ciPath
+	}
+	return nil
+}
+
+// Continues the case-insensitive lookup with the value of the paramNode n ending at end
+func (n *Node) findCaseInsensitiveParam(path string, end int, ciPath []byte, fixTrailingSlash bool) []byte {
+	if n.constraint != nil && !n.constraint.match(path[:end]) {
+		return nil
+	}
+
+	// Add paramNode value to case insensitive path
+	out := append(ciPath, path[:end]...) //nolint:gocritic
+
+	// We need to go deeper!
+	if end < len(path) {
+		if len(n.children) > 0 {
+			return n.children[0].findCaseInsensitivePathRec(path[end:], out, [4]byte{}, fixTrailingSlash)
+		}
+		if fixTrailingSlash && len(path) == end+1 {
+			return out
+		}
+		return nil
+	}
+
+	if n.handlers != nil {
+		return out
+	}
+
+	if fixTrailingSlash && len(n.children) == 1 {
+		// No handle found. Check if a handle for this path + a
+		// trailing slash exists
+		child := n.children[0]
+		if child.path == "/" && child.handlers != nil {
+			return append(out, '/')
+		}
+	}
+	return nil
+}
+
+// Continues the case-insensitive lookup with the static child indexed by c
+func (n *Node) findCaseInsensitiveChild(c byte, path string, ciPath []byte, rb [4]byte, fixTrailingSlash bool) []byte {
+	for i, idx := range []byte(n.indices) {
+		if idx == c {
+			return n.children[i].findCaseInsensitivePathRec(path, ciPath, rb, fixTrailingSlash)
+		}
+	}
+	return nil
+}
@@ -3,6 +3,8 @@ package tsing
 import (
 	"bytes"
 	"path"
+	"reflect"
+	"runtime"
 	"strings"
 )
 
@@ -35,6 +37,26 @@ func joinPaths(absolutePath, relativePath string) string {
 	return finalPath
 }
 
+// cleanPath 清理路径中的'.'、'..'和多余的'/'，并保留末尾的'/'
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	finalPath := path.Clean(p)
+	if p[len(p)-1] == '/' && finalPath != "/" {
+		return finalPath + "/"
+	}
+	return finalPath
+}
+
+// nameOfFunction 获取函数的完整名称
+func nameOfFunction(f any) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
 // minNumber 返回两个整数中的较小值
 func minNumber(a, b int) int {
 	if a <= b {
@@ -63,3 +85,13 @@ func countParams(path string) int {
 func countSections(path string) int {
 	return bytes.Count(strToBytes(path), strSlash)
 }
+
+// containsString 判断切片中是否包含指定的字符串
+func containsString(s []string, v string) bool {
+	for k := range s {
+		if s[k] == v {
+			return true
+		}
+	}
+	return false
+}
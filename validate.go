@@ -0,0 +1,290 @@
+package tsing
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Validator 结构体验证器，通过 Config.Validator 替换内置的 validate 标签验证器，
+// 由 Validate 和 BindAndValidate 调用
+type Validator interface {
+	Validate(obj any) error
+}
+
+// ValidationError 单个字段未通过的验证规则
+type ValidationError struct {
+	Field string `json:"field"`           // 结构体字段名，嵌套结构体的字段以'.'连接
+	Rule  string `json:"rule"`            // 规则名，例如 required、min
+	Param string `json:"param,omitempty"` // 规则参数，例如 min=1 中的 1
+}
+
+func (e ValidationError) Error() string {
+	if e.Param == "" {
+		return "field '" + e.Field + "' failed on the '" + e.Rule + "' rule"
+	}
+	return "field '" + e.Field + "' failed on the '" + e.Rule + "=" + e.Param + "' rule"
+}
+
+// ValidationErrors 验证失败的所有字段，处理器返回该错误时响应422状态码
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i := range e {
+		msgs[i] = e[i].Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// BindAndValidate 依次使用 Bind 绑定路径参数、查询参数等，使用 BindBody 解码请求体，全部绑定完成之后使用 Validate 验证 obj，
+// 用于同一个结构体中包含多个来源的字段的情况。分别调用 Bind 和 BindBody 时需要在最后自行调用 Validate，
+// 以免在请求体解码之前验证请求体中的字段
+func (ctx *Context) BindAndValidate(obj any) error {
+	if err := ctx.Bind(obj); err != nil {
+		return err
+	}
+	if err := ctx.BindBody(obj); err != nil {
+		return err
+	}
+	return ctx.Validate(obj)
+}
+
+// Validate 使用 Config.Validator 验证 obj，未设置时使用内置的 validate 标签验证器
+func (ctx *Context) Validate(obj any) error {
+	if ctx.engine.config.Validator != nil {
+		return ctx.engine.config.Validator.Validate(obj)
+	}
+	return defaultValidator.Validate(obj)
+}
+
+// defaultValidator 内置的验证器，根据结构体字段的 validate 标签验证，例如：
+//
+//	Name  string `validate:"required,max=20"`
+//	Email string `validate:"email"`
+//	Role  string `validate:"oneof=admin user"`
+//
+// 内置规则：
+//   - required 不能是零值，切片、map 的长度不能为0
+//   - min、max 字符串的字符数、切片和 map 的长度、数字的值的范围
+//   - len 字符串的字符数、切片和 map 的长度、数字的值等于参数
+//   - email 电子邮件地址
+//   - oneof 等于以空格分隔的参数中的一个
+//
+// 值为nil的指针字段只检查 required 规则，没有 validate 标签的结构体字段会验证其中的字段。
+// obj 不是结构体指针时不验证
+var defaultValidator Validator = tagValidator{}
+
+type tagValidator struct{}
+
+// validateCache 缓存结构体类型的验证规则 map[reflect.Type]*validateStruct
+var validateCache sync.Map
+
+type validateRule struct {
+	name  string
+	param string
+	check func(v reflect.Value) bool
+}
+
+type validateField struct {
+	index  []int
+	name   string
+	rules  []validateRule
+	nested bool // 没有 validate 标签的结构体或结构体指针字段，验证其中的字段
+}
+
+type validateStruct struct {
+	fields []validateField
+	err    error // 解析 validate 标签时的错误
+}
+
+func (tagValidator) Validate(obj any) error {
+	rv := reflect.ValueOf(obj)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	var errs ValidationErrors
+	if err := validateValue(rv.Elem(), "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateValue(rv reflect.Value, prefix string, errs *ValidationErrors) error {
+	info := getValidateStruct(rv.Type())
+	if info.err != nil {
+		return info.err
+	}
+	for i := range info.fields {
+		field := &info.fields[i]
+		v := rv.FieldByIndex(field.index)
+		if field.nested {
+			if v.Kind() == reflect.Pointer {
+				if v.IsNil() {
+					continue
+				}
+				v = v.Elem()
+			}
+			nestedPrefix := prefix
+			if field.name != "" {
+				nestedPrefix += field.name + "."
+			}
+			if err := validateValue(v, nestedPrefix, errs); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, rule := range field.rules {
+			if !rule.check(v) {
+				*errs = append(*errs, ValidationError{Field: prefix + field.name, Rule: rule.name, Param: rule.param})
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// getValidateStruct 获取结构体类型的验证规则，解析结果会被缓存
+func getValidateStruct(t reflect.Type) *validateStruct {
+	if info, ok := validateCache.Load(t); ok {
+		return info.(*validateStruct) //nolint:forcetypeassert
+	}
+	info := &validateStruct{}
+	info.fields, info.err = parseValidateFields(t)
+	actual, _ := validateCache.LoadOrStore(t, info)
+	return actual.(*validateStruct) //nolint:forcetypeassert
+}
+
+func parseValidateFields(t reflect.Type) ([]validateField, error) {
+	var fields []validateField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() && !sf.Anonymous {
+			continue
+		}
+		tag := sf.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+		if tag == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft != timeType {
+				field := validateField{index: sf.Index, name: sf.Name, nested: true}
+				if sf.Anonymous {
+					field.name = "" // 嵌入的结构体的字段使用提升后的字段名
+				}
+				fields = append(fields, field)
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		field := validateField{index: sf.Index, name: sf.Name}
+		for _, item := range strings.Split(tag, ",") {
+			name, param, _ := strings.Cut(strings.TrimSpace(item), "=")
+			check, err := compileRule(name, param, sf.Type)
+			if err != nil {
+				return nil, fmt.Errorf("invalid validate rule '%s' of field '%s' in %s: %w", item, sf.Name, t, err)
+			}
+			field.rules = append(field.rules, validateRule{name: name, param: param, check: check})
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// compileRule 编译验证规则，返回的函数对值为nil的指针字段只检查 required 规则
+func compileRule(name, param string, t reflect.Type) (func(v reflect.Value) bool, error) {
+	var check func(v reflect.Value) bool
+	switch name {
+	case "required":
+		return func(v reflect.Value) bool {
+			switch v.Kind() { //nolint:exhaustive
+			case reflect.Slice, reflect.Map:
+				return v.Len() > 0
+			default:
+				return !v.IsZero()
+			}
+		}, nil
+	case "min", "max", "len":
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return nil, err
+		}
+		check = func(v reflect.Value) bool {
+			size, ok := sizeOf(v)
+			if !ok {
+				return false
+			}
+			switch name {
+			case "min":
+				return size >= n
+			case "max":
+				return size <= n
+			default:
+				return size == n
+			}
+		}
+	case "email":
+		check = func(v reflect.Value) bool {
+			if v.Kind() != reflect.String {
+				return false
+			}
+			addr, err := mail.ParseAddress(v.String())
+			return err == nil && addr.Address == v.String()
+		}
+	case "oneof":
+		if param == "" {
+			return nil, errors.New("oneof requires at least one value")
+		}
+		options := strings.Fields(param)
+		check = func(v reflect.Value) bool {
+			value := fmt.Sprint(v.Interface())
+			for _, option := range options {
+				if value == option {
+					return true
+				}
+			}
+			return false
+		}
+	default:
+		return nil, errors.New("unknown rule")
+	}
+
+	if t.Kind() != reflect.Pointer {
+		return check, nil
+	}
+	return func(v reflect.Value) bool {
+		return v.IsNil() || check(v.Elem())
+	}, nil
+}
+
+// sizeOf 返回字符串的字符数、切片和 map 的长度、数字的值
+func sizeOf(v reflect.Value) (float64, bool) {
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}